@@ -0,0 +1,281 @@
+package suplog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy defines what AsyncWriter does when its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the logging call until there is room in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the entry being written.
+	OverflowDropNewest
+	// OverflowDropBelowLevel drops the entry being written if it is less severe
+	// than AsyncOptions.DropLevel, blocks otherwise.
+	OverflowDropBelowLevel
+)
+
+// ParseOverflowPolicy takes a string policy name and returns the policy constant.
+func ParseOverflowPolicy(name string) (policy OverflowPolicy, err error) {
+	switch name {
+	case "block":
+		policy = OverflowBlock
+	case "drop", "drop_newest":
+		policy = OverflowDropNewest
+	case "drop_below":
+		policy = OverflowDropBelowLevel
+	default:
+		err = fmt.Errorf("not a valid overflow policy: %s", name)
+	}
+
+	return
+}
+
+// AsyncOptions allows to set AsyncWriter options.
+type AsyncOptions struct {
+	// QueueSize is the amount of entries buffered before the overflow policy kicks in.
+	QueueSize int
+	// Overflow specifies what to do when the queue is full.
+	Overflow OverflowPolicy
+	// DropLevel is the least severe level that is never dropped by OverflowDropBelowLevel.
+	DropLevel Level
+}
+
+const defaultAsyncQueueSize = 1024
+
+func checkAsyncOptions(opt *AsyncOptions) *AsyncOptions {
+	if opt == nil {
+		opt = &AsyncOptions{}
+	}
+
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = defaultAsyncQueueSize
+	}
+
+	return opt
+}
+
+// ErrAsyncWriterClosed is returned on writes into a closed AsyncWriter.
+var ErrAsyncWriterClosed = errors.New("async writer is closed")
+
+// AsyncWriter is a buffered writer that writes into the underlying writer
+// in background, so slow outputs don't stall logging goroutines.
+// When used as logger output, the level of entries is known to the writer,
+// otherwise plain writes are considered to be of PanicLevel.
+type AsyncWriter struct {
+	opt *AsyncOptions
+
+	outMux sync.Mutex
+	out    io.Writer
+
+	mux    sync.RWMutex
+	closed bool
+	queue  chan asyncItem
+	done   chan struct{}
+
+	dropped uint64
+	// terminal is set when the underlying writer is a terminal,
+	// so formatters could detect it without locking the output
+	terminal int32
+}
+
+type asyncItem struct {
+	data    []byte
+	flushed chan struct{}
+}
+
+// NewAsyncWriter wraps the writer and starts the background writer routine.
+func NewAsyncWriter(wr io.Writer, opt *AsyncOptions) *AsyncWriter {
+	opt = checkAsyncOptions(opt)
+
+	w := &AsyncWriter{
+		opt:   opt,
+		out:   wr,
+		queue: make(chan asyncItem, opt.QueueSize),
+		done:  make(chan struct{}),
+	}
+
+	w.setTerminal(wr)
+
+	go w.run()
+
+	return w
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+
+	for item := range w.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+
+		w.outMux.Lock()
+		if _, err := w.out.Write(item.data); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+		}
+		w.outMux.Unlock()
+	}
+}
+
+// Write enqueues a copy of p, applying the overflow policy if the queue is full.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(PanicLevel, p)
+}
+
+// WriteLevel enqueues a copy of p written on the given level.
+func (w *AsyncWriter) WriteLevel(level Level, p []byte) (int, error) {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+
+	if w.closed {
+		return 0, ErrAsyncWriterClosed
+	}
+
+	item := asyncItem{
+		data: make([]byte, len(p)),
+	}
+	copy(item.data, p)
+
+	switch {
+	case w.opt.Overflow == OverflowDropNewest,
+		w.opt.Overflow == OverflowDropBelowLevel && level > w.opt.DropLevel:
+		select {
+		case w.queue <- item:
+		default:
+			atomic.AddUint64(&w.dropped, 1)
+		}
+	default:
+		w.queue <- item
+	}
+
+	return len(p), nil
+}
+
+// Dropped returns the amount of entries dropped due to the queue overflow.
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Flush blocks until all entries queued before the call are written.
+func (w *AsyncWriter) Flush() {
+	w.mux.RLock()
+	if w.closed {
+		w.mux.RUnlock()
+		return
+	}
+
+	flushed := make(chan struct{})
+	w.queue <- asyncItem{
+		flushed: flushed,
+	}
+	w.mux.RUnlock()
+
+	<-flushed
+}
+
+// SetOutput flushes the queue and replaces the underlying writer.
+func (w *AsyncWriter) SetOutput(wr io.Writer) {
+	w.Flush()
+
+	w.outMux.Lock()
+	w.out = wr
+	w.setTerminal(wr)
+	w.outMux.Unlock()
+}
+
+func (w *AsyncWriter) setTerminal(wr io.Writer) {
+	var terminal int32
+	if isTerminal(wr) {
+		terminal = 1
+	}
+
+	atomic.StoreInt32(&w.terminal, terminal)
+}
+
+// isTerminal reports whether the underlying writer is a terminal.
+func (w *AsyncWriter) isTerminal() bool {
+	return atomic.LoadInt32(&w.terminal) == 1
+}
+
+// Close drains the queue and closes the underlying writer,
+// if it implements io.WriteCloser.
+func (w *AsyncWriter) Close() error {
+	w.mux.Lock()
+	if w.closed {
+		w.mux.Unlock()
+		return nil
+	}
+
+	w.closed = true
+	close(w.queue)
+	w.mux.Unlock()
+
+	<-w.done
+
+	w.outMux.Lock()
+	defer w.outMux.Unlock()
+
+	if outCloser, ok := w.out.(io.WriteCloser); ok {
+		return outCloser.Close()
+	}
+
+	return nil
+}
+
+// levelHeaderMagic starts a level header that levelFormatter prepends to entries,
+// formatted entries never start with a NUL byte.
+const levelHeaderMagic = 0x00
+
+// levelFormatter passes the level of formatted entries to AsyncWriter,
+// prepending a header that is stripped by levelWriter.
+type levelFormatter struct {
+	Formatter
+}
+
+func (f *levelFormatter) Format(e *Entry) ([]byte, error) {
+	data, err := f.Formatter.Format(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{levelHeaderMagic, byte(e.Level)}, data...), nil
+}
+
+// levelWriter strips the level header and writes entries into AsyncWriter on that level.
+type levelWriter struct {
+	w *AsyncWriter
+}
+
+func (lw *levelWriter) Write(p []byte) (int, error) {
+	if len(p) < 2 || p[0] != levelHeaderMagic {
+		return lw.w.Write(p)
+	}
+
+	n, err := lw.w.WriteLevel(Level(p[1]), p[2:])
+	if err != nil {
+		return n, err
+	}
+
+	return len(p), nil
+}
+
+// asyncOutput discovers the AsyncWriter behind the output, if any.
+func asyncOutput(wr io.Writer) (*AsyncWriter, bool) {
+	switch w := wr.(type) {
+	case *AsyncWriter:
+		return w, true
+	case *levelWriter:
+		return w.w, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,150 @@
+package suplog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gatedWriter blocks all writes until the gate is opened.
+type gatedWriter struct {
+	gate chan struct{}
+
+	mux    sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.gate
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) Close() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	w.closed = true
+
+	return nil
+}
+
+func TestAsyncWriterDropBelowLevel(t *testing.T) {
+	out := &gatedWriter{
+		gate: make(chan struct{}),
+	}
+
+	aw := NewAsyncWriter(out, &AsyncOptions{
+		QueueSize: 2,
+		Overflow:  OverflowDropBelowLevel,
+		DropLevel: WarnLevel,
+	})
+
+	logger := NewLogger(aw, new(JSONFormatter))
+
+	// the first entry is taken by the background writer and blocks on the gate,
+	// others fill the queue, so the rest of debug entries are dropped.
+	for i := 0; i < 10; i++ {
+		logger.Debugf("debug %d", i)
+	}
+
+	if aw.Dropped() == 0 {
+		t.Errorf("expected debug entries to be dropped")
+	}
+
+	dropped := aw.Dropped()
+	close(out.gate)
+	logger.Error("error is never dropped")
+
+	if err := logger.(*suplogger).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if aw.Dropped() != dropped {
+		t.Errorf("expected no more entries dropped, got %d", aw.Dropped()-dropped)
+	}
+
+	if !out.closed {
+		t.Errorf("expected underlying writer to be closed")
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.buf.String()), "\n")
+	if uint64(len(lines)) != 11-dropped {
+		t.Errorf("expected %d lines, got %d", 11-dropped, len(lines))
+	}
+
+	if !strings.Contains(lines[len(lines)-1], "error is never dropped") {
+		t.Errorf("expected error entry to be written last, got %s", lines[len(lines)-1])
+	}
+}
+
+func TestAsyncWriterFlush(t *testing.T) {
+	out := &gatedWriter{
+		gate: make(chan struct{}),
+	}
+	close(out.gate)
+
+	logger, err := NewLoggerWithConfig(&Config{
+		Level:     DebugLevel,
+		Formatter: "json",
+		Async:     &AsyncOptions{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.(LoggerConfigurator).SetOutput(out)
+	logger.Info("flushed")
+	logger.(*suplogger).async.Flush()
+
+	out.mux.Lock()
+	defer out.mux.Unlock()
+
+	if !strings.Contains(out.buf.String(), `"msg":"flushed"`) {
+		t.Errorf("expected entry to be flushed, got %s", out.buf.String())
+	}
+}
+
+func TestLoggerDropped(t *testing.T) {
+	out := &gatedWriter{
+		gate: make(chan struct{}),
+	}
+
+	logger, err := NewLoggerWithConfig(&Config{
+		Level: DebugLevel,
+		Async: &AsyncOptions{
+			QueueSize: 2,
+			Overflow:  OverflowDropNewest,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.(LoggerConfigurator).SetOutput(out)
+
+	for i := 0; i < 10; i++ {
+		logger.Debugf("debug %d", i)
+	}
+
+	named := logger.Named("db")
+	if logger.Dropped() == 0 || named.Dropped() != logger.Dropped() {
+		t.Errorf("expected dropped entries reported by derived loggers too, got %d and %d",
+			logger.Dropped(), named.Dropped())
+	}
+
+	close(out.gate)
+
+	if err := logger.(*suplogger).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if dropped := NewLogger(nil, nil).Dropped(); dropped != 0 {
+		t.Errorf("expected no entries dropped by synchronous output, got %d", dropped)
+	}
+}
@@ -0,0 +1,199 @@
+// Package batcher implements the background queue of hooks exporting entries
+// in batches: entries are queued without blocking, batched and exported once
+// the batch is full, on the flush interval and on Flush.
+package batcher
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options of the Batcher. Add, Export and Stop are called from the background
+// goroutine only, so the batch needs no locking.
+type Options struct {
+	// QueueSize limits items waiting to be batched, newer items are dropped
+	// when the queue is full.
+	QueueSize int
+	// FlushInterval is the maximum time items wait in the batch.
+	FlushInterval time.Duration
+	// Add adds the item to the current batch, reporting whether the batch is full.
+	Add func(item interface{}) (full bool)
+	// Export sends the current batch, the batch may be empty.
+	Export func()
+	// Dropped reports items dropped since the previous export, if any.
+	Dropped func(n uint64)
+	// Stop is called once the batcher is closed, optional.
+	Stop func()
+}
+
+// Batcher queues items and exports them in batches in background.
+type Batcher struct {
+	opt Options
+
+	queue   chan message
+	done    chan struct{}
+	wg      sync.WaitGroup
+	closed  int32
+	dropped uint64
+
+	closeOnce sync.Once
+}
+
+// message is either an item, or a flush marker.
+type message struct {
+	item    interface{}
+	flushed chan struct{}
+}
+
+// New starts the batcher, call Close to stop it.
+func New(opt Options) *Batcher {
+	b := &Batcher{
+		opt:   opt,
+		queue: make(chan message, opt.QueueSize),
+		done:  make(chan struct{}),
+	}
+
+	b.wg.Add(1)
+	go b.run()
+
+	return b
+}
+
+// Enqueue queues the item without blocking, reporting whether it was queued.
+// Items are dropped when the queue is full, or the batcher is closed.
+func (b *Batcher) Enqueue(item interface{}) bool {
+	if b.Closed() {
+		return false
+	}
+
+	select {
+	case b.queue <- message{item: item}:
+		return true
+	default:
+		b.Drop()
+		return false
+	}
+}
+
+// Drop counts the item dropped before queueing, it is reported with the next export.
+func (b *Batcher) Drop() {
+	atomic.AddUint64(&b.dropped, 1)
+}
+
+// Closed reports whether the batcher is closed.
+func (b *Batcher) Closed() bool {
+	return atomic.LoadInt32(&b.closed) == 1
+}
+
+// Wait blocks for d, reporting false if the batcher is closed meanwhile.
+func (b *Batcher) Wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-b.done:
+		return false
+	}
+}
+
+// Flush blocks until items queued before the call are exported, or ctx is done.
+func (b *Batcher) Flush(ctx context.Context) error {
+	if b.Closed() {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+
+	select {
+	case b.queue <- message{flushed: flushed}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close exports queued items and stops the batcher.
+func (b *Batcher) Close(ctx context.Context) error {
+	err := b.Flush(ctx)
+
+	b.closeOnce.Do(func() {
+		atomic.StoreInt32(&b.closed, 1)
+		close(b.done)
+	})
+
+	b.wg.Wait()
+
+	return err
+}
+
+// run batches queued items and exports batches until the batcher is closed.
+func (b *Batcher) run() {
+	defer b.wg.Done()
+
+	if b.opt.Stop != nil {
+		defer b.opt.Stop()
+	}
+
+	ticker := time.NewTicker(b.opt.FlushInterval)
+	defer ticker.Stop()
+
+	export := func() {
+		b.opt.Export()
+
+		if n := atomic.SwapUint64(&b.dropped, 0); n > 0 && b.opt.Dropped != nil {
+			b.opt.Dropped(n)
+		}
+	}
+
+	for {
+		select {
+		case msg := <-b.queue:
+			if msg.flushed != nil {
+				export()
+				close(msg.flushed)
+				continue
+			}
+
+			if b.opt.Add(msg.item) {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// Backoff doubles delays between retries, starting from Min up to Max.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+
+	next time.Duration
+}
+
+// Next returns the delay before the next retry.
+func (b *Backoff) Next() time.Duration {
+	if b.next == 0 {
+		b.next = b.Min
+	}
+
+	d := b.next
+
+	if b.next *= 2; b.next > b.Max {
+		b.next = b.Max
+	}
+
+	return d
+}
@@ -0,0 +1,179 @@
+package batcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects exported batches.
+type recorder struct {
+	mux     sync.Mutex
+	current []interface{}
+	batches [][]interface{}
+	dropped uint64
+	stopped bool
+}
+
+func newBatcher(r *recorder, queueSize, batchSize int, interval time.Duration) *Batcher {
+	return New(Options{
+		QueueSize:     queueSize,
+		FlushInterval: interval,
+		Add: func(item interface{}) bool {
+			r.current = append(r.current, item)
+			return len(r.current) >= batchSize
+		},
+		Export: func() {
+			r.mux.Lock()
+			defer r.mux.Unlock()
+
+			if len(r.current) > 0 {
+				r.batches = append(r.batches, r.current)
+				r.current = nil
+			}
+		},
+		Dropped: func(n uint64) {
+			r.mux.Lock()
+			defer r.mux.Unlock()
+
+			r.dropped += n
+		},
+		Stop: func() {
+			r.stopped = true
+		},
+	})
+}
+
+func (r *recorder) exported() ([][]interface{}, uint64) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return r.batches, r.dropped
+}
+
+func flush(t *testing.T, b *Batcher) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := b.Flush(ctx); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+}
+
+func TestBatcherBatchSize(t *testing.T) {
+	r := new(recorder)
+	b := newBatcher(r, 10, 2, time.Hour)
+	defer b.Close(context.Background())
+
+	for i := 0; i < 5; i++ {
+		b.Enqueue(i)
+	}
+
+	flush(t, b)
+
+	if batches, _ := r.exported(); len(batches) != 3 || len(batches[0]) != 2 || len(batches[2]) != 1 {
+		t.Errorf("expected full batches exported and the rest on flush, got %v", batches)
+	}
+}
+
+func TestBatcherFlushInterval(t *testing.T) {
+	r := new(recorder)
+	b := newBatcher(r, 10, 100, 10*time.Millisecond)
+	defer b.Close(context.Background())
+
+	b.Enqueue("first")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if batches, _ := r.exported(); len(batches) == 1 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("expected the batch exported on the flush interval")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBatcherDropped(t *testing.T) {
+	r := new(recorder)
+
+	// blocks the background goroutine until released
+	release := make(chan struct{})
+	b := New(Options{
+		QueueSize:     1,
+		FlushInterval: time.Hour,
+		Add: func(item interface{}) bool {
+			<-release
+			return false
+		},
+		Export: func() {},
+		Dropped: func(n uint64) {
+			r.mux.Lock()
+			defer r.mux.Unlock()
+
+			r.dropped += n
+		},
+	})
+	defer b.Close(context.Background())
+
+	b.Enqueue("taken")
+
+	// wait for the first item to be taken from the queue
+	for len(b.queue) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if !b.Enqueue("queued") || b.Enqueue("dropped") {
+		t.Error("expected items over the queue size dropped")
+	}
+
+	b.Drop()
+	close(release)
+	flush(t, b)
+
+	if _, dropped := r.exported(); dropped != 2 {
+		t.Errorf("expected 2 dropped items reported, got %d", dropped)
+	}
+}
+
+func TestBatcherClose(t *testing.T) {
+	r := new(recorder)
+	b := newBatcher(r, 10, 100, time.Hour)
+
+	b.Enqueue("queued")
+
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if batches, _ := r.exported(); len(batches) != 1 || !r.stopped {
+		t.Errorf("expected queued items exported and batcher stopped, got %v", batches)
+	}
+
+	if b.Enqueue("closed") || b.Wait(time.Hour) {
+		t.Error("expected closed batcher to drop items and stop waiting")
+	}
+
+	if err := b.Close(context.Background()); err != nil {
+		t.Errorf("expected repeated Close to succeed, got %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	b := &Backoff{
+		Min: time.Second,
+		Max: 5 * time.Second,
+	}
+
+	exp := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+
+	for i, d := range exp {
+		if next := b.Next(); next != d {
+			t.Errorf("expected %s delay of retry %d, got %s", d, i, next)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package suplog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xlab/closer"
+
+	debugHook "github.com/xlab/suplog/hooks/debug"
+	stackHook "github.com/xlab/suplog/hooks/stack"
+	"github.com/xlab/suplog/output/file"
+	"github.com/xlab/suplog/sampler"
+)
+
+// Config describes the logger setup shared by NewLogger, NewLoggerWithConfig
+// and the default logger. Use ConfigFromEnv to get a config from OS ENV variables.
+type Config struct {
+	// Level sets the root logger level (LOG_LEVEL), debug if unset. PanicLevel is
+	// the zero value, so it can't be set here, use SetLevel to log panics only.
+	Level Level
+	// Levels sets levels of named loggers (LOG_LEVELS), see ParseLevels.
+	Levels map[string]Level
+	// Output is either "stderr", "stdout" or a file path (LOG_OUTPUT), stderr by default.
+	Output string
+	// File sets options of the rotating file output, used when Output is a file path.
+	// Mapped from LOG_FILE_MAX_SIZE, LOG_FILE_ROTATE_INTERVAL, LOG_FILE_COMPRESS,
+	// LOG_FILE_MAX_AGE and LOG_FILE_MAX_BACKUPS.
+	File *file.Options
+	// Formatter is either "text", "json", "pretty", "cli", "logfmt", "ecs", "gelf"
+	// or "gcp" (LOG_FORMATTER), text by default. The gcp formatter correlates
+	// traces of the project set by GOOGLE_CLOUD_PROJECT.
+	Formatter string
+	// TimestampFormat overrides timestamp layout of the formatter (LOG_TIMESTAMP_FORMAT).
+	TimestampFormat string
+	// DebugHook enables the debug hook with given options, nil disables it.
+	// Mapped from LOG_DEBUG_HOOK, LOG_DEBUG_LEVELS, LOG_DEBUG_PATH_SEGMENTS
+	// and LOG_DEBUG_STACK_OFFSET.
+	DebugHook *debugHook.HookOptions
+	// StackHook enables the stack hook with given options, nil disables it.
+	// Mapped from LOG_STACK_LEVELS and LOG_STACK_MAX_FRAMES. The text formatter
+	// renders stacks as indented lines when enabled.
+	StackHook *stackHook.HookOptions
+	// Sampling enables sampling of entries with given options, nil disables it.
+	// Mapped from LOG_SAMPLING (see sampler.ParseRules) and LOG_SAMPLING_KEY.
+	Sampling *sampler.Options
+	// Async enables asynchronous output with given options, nil disables it.
+	// Mapped from LOG_ASYNC, LOG_ASYNC_QUEUE_SIZE, LOG_ASYNC_OVERFLOW
+	// and LOG_ASYNC_DROP_LEVEL.
+	Async *AsyncOptions
+	// Dedup enables suppression of consecutive duplicate entries with given options,
+	// nil disables it. Mapped from LOG_DEDUP_WINDOW.
+	Dedup *DedupOptions
+	// FlushTimeout bounds flushing of hooks on Close and Fatal (LOG_FLUSH_TIMEOUT), 5s by default.
+	FlushTimeout time.Duration
+	// AfterClose specifies what happens to entries logged after Close (LOG_AFTER_CLOSE),
+	// either "stderr" (default) or "drop".
+	AfterClose AfterClosePolicy
+	// ExitFunc is called after logging on Fatal level, closer.Exit by default.
+	ExitFunc func(code int)
+}
+
+// ConfigFromEnv reads the logger config from OS ENV variables,
+// invalid values are reported to stderr and replaced with defaults.
+func ConfigFromEnv() *Config {
+	cfg := &Config{
+		Level:           DebugLevel,
+		Output:          os.Getenv("LOG_OUTPUT"),
+		Formatter:       os.Getenv("LOG_FORMATTER"),
+		TimestampFormat: os.Getenv("LOG_TIMESTAMP_FORMAT"),
+		ExitFunc:        closer.Exit,
+	}
+
+	if levelName := os.Getenv("LOG_LEVEL"); len(levelName) > 0 {
+		if level, err := ParseLevel(levelName); err != nil {
+			reportConfigErr("LOG_LEVEL", err)
+		} else if level == PanicLevel {
+			reportConfigErr("LOG_LEVEL", fmt.Errorf("%s level is not supported, use fatal", level))
+		} else {
+			cfg.Level = level
+		}
+	}
+
+	if spec := os.Getenv("LOG_LEVELS"); len(spec) > 0 {
+		if levels, err := ParseLevels(spec); err != nil {
+			reportConfigErr("LOG_LEVELS", err)
+		} else {
+			cfg.Levels = levels
+		}
+	}
+
+	cfg.File = fileOptionsFromEnv()
+
+	if v := os.Getenv("LOG_DEBUG_HOOK"); len(v) == 0 || isTrue(v) {
+		cfg.DebugHook = debugHookOptionsFromEnv()
+	}
+
+	if v := os.Getenv("LOG_STACK_LEVELS"); len(v) > 0 {
+		cfg.StackHook = stackHookOptionsFromEnv(v)
+	}
+
+	if spec := os.Getenv("LOG_SAMPLING"); len(spec) > 0 {
+		cfg.Sampling = samplingOptionsFromEnv(spec)
+	}
+
+	if isTrue(os.Getenv("LOG_ASYNC")) {
+		cfg.Async = asyncOptionsFromEnv()
+	}
+
+	if v := os.Getenv("LOG_DEDUP_WINDOW"); len(v) > 0 {
+		if window, err := time.ParseDuration(v); err != nil {
+			reportConfigErr("LOG_DEDUP_WINDOW", err)
+		} else {
+			cfg.Dedup = &DedupOptions{
+				Window: window,
+			}
+		}
+	}
+
+	if v := os.Getenv("LOG_FLUSH_TIMEOUT"); len(v) > 0 {
+		if timeout, err := time.ParseDuration(v); err != nil {
+			reportConfigErr("LOG_FLUSH_TIMEOUT", err)
+		} else {
+			cfg.FlushTimeout = timeout
+		}
+	}
+
+	if v := os.Getenv("LOG_AFTER_CLOSE"); len(v) > 0 {
+		if policy, err := ParseAfterClosePolicy(v); err != nil {
+			reportConfigErr("LOG_AFTER_CLOSE", err)
+		} else {
+			cfg.AfterClose = policy
+		}
+	}
+
+	return cfg
+}
+
+func samplingOptionsFromEnv(spec string) *sampler.Options {
+	rules, err := sampler.ParseRules(spec)
+	if err != nil {
+		reportConfigErr("LOG_SAMPLING", err)
+		return nil
+	}
+
+	opt := &sampler.Options{
+		Rules: rules,
+	}
+
+	if v := os.Getenv("LOG_SAMPLING_KEY"); len(v) > 0 {
+		if keyBy, err := sampler.ParseKeyBy(v); err != nil {
+			reportConfigErr("LOG_SAMPLING_KEY", err)
+		} else {
+			opt.KeyBy = keyBy
+		}
+	}
+
+	return opt
+}
+
+func asyncOptionsFromEnv() *AsyncOptions {
+	opt := &AsyncOptions{
+		DropLevel: InfoLevel,
+	}
+
+	if v := os.Getenv("LOG_ASYNC_QUEUE_SIZE"); len(v) > 0 {
+		if n, err := strconv.Atoi(v); err != nil {
+			reportConfigErr("LOG_ASYNC_QUEUE_SIZE", err)
+		} else {
+			opt.QueueSize = n
+		}
+	}
+
+	if v := os.Getenv("LOG_ASYNC_OVERFLOW"); len(v) > 0 {
+		if policy, err := ParseOverflowPolicy(v); err != nil {
+			reportConfigErr("LOG_ASYNC_OVERFLOW", err)
+		} else {
+			opt.Overflow = policy
+		}
+	}
+
+	if v := os.Getenv("LOG_ASYNC_DROP_LEVEL"); len(v) > 0 {
+		if level, err := ParseLevel(v); err != nil {
+			reportConfigErr("LOG_ASYNC_DROP_LEVEL", err)
+		} else {
+			opt.DropLevel = level
+		}
+	}
+
+	return opt
+}
+
+func fileOptionsFromEnv() *file.Options {
+	opt := &file.Options{
+		Compress: isTrue(os.Getenv("LOG_FILE_COMPRESS")),
+	}
+
+	if v := os.Getenv("LOG_FILE_MAX_SIZE"); len(v) > 0 {
+		if n, err := strconv.ParseInt(v, 10, 64); err != nil {
+			reportConfigErr("LOG_FILE_MAX_SIZE", err)
+		} else {
+			opt.MaxSize = n
+		}
+	}
+
+	if v := os.Getenv("LOG_FILE_ROTATE_INTERVAL"); len(v) > 0 {
+		if d, err := time.ParseDuration(v); err != nil {
+			reportConfigErr("LOG_FILE_ROTATE_INTERVAL", err)
+		} else {
+			opt.Interval = d
+		}
+	}
+
+	if v := os.Getenv("LOG_FILE_MAX_AGE"); len(v) > 0 {
+		if d, err := time.ParseDuration(v); err != nil {
+			reportConfigErr("LOG_FILE_MAX_AGE", err)
+		} else {
+			opt.MaxAge = d
+		}
+	}
+
+	if v := os.Getenv("LOG_FILE_MAX_BACKUPS"); len(v) > 0 {
+		if n, err := strconv.Atoi(v); err != nil {
+			reportConfigErr("LOG_FILE_MAX_BACKUPS", err)
+		} else {
+			opt.MaxBackups = n
+		}
+	}
+
+	return opt
+}
+
+func debugHookOptionsFromEnv() *debugHook.HookOptions {
+	opt := &debugHook.HookOptions{}
+
+	if v := os.Getenv("LOG_DEBUG_LEVELS"); len(v) > 0 {
+		for _, levelName := range strings.Split(v, ",") {
+			level, err := ParseLevel(strings.TrimSpace(levelName))
+			if err != nil {
+				reportConfigErr("LOG_DEBUG_LEVELS", err)
+				continue
+			}
+
+			opt.Levels = append(opt.Levels, level)
+		}
+	}
+
+	if v := os.Getenv("LOG_DEBUG_PATH_SEGMENTS"); len(v) > 0 {
+		if n, err := strconv.Atoi(v); err != nil {
+			reportConfigErr("LOG_DEBUG_PATH_SEGMENTS", err)
+		} else {
+			opt.PathSegmentsLimit = n
+		}
+	}
+
+	if v := os.Getenv("LOG_DEBUG_STACK_OFFSET"); len(v) > 0 {
+		if n, err := strconv.Atoi(v); err != nil {
+			reportConfigErr("LOG_DEBUG_STACK_OFFSET", err)
+		} else {
+			opt.StackTraceOffset = n
+		}
+	}
+
+	return opt
+}
+
+func stackHookOptionsFromEnv(levels string) *stackHook.HookOptions {
+	opt := &stackHook.HookOptions{}
+
+	for _, levelName := range strings.Split(levels, ",") {
+		level, err := ParseLevel(strings.TrimSpace(levelName))
+		if err != nil {
+			reportConfigErr("LOG_STACK_LEVELS", err)
+			continue
+		}
+
+		opt.Levels = append(opt.Levels, level)
+	}
+
+	if v := os.Getenv("LOG_STACK_MAX_FRAMES"); len(v) > 0 {
+		if n, err := strconv.Atoi(v); err != nil {
+			reportConfigErr("LOG_STACK_MAX_FRAMES", err)
+		} else {
+			opt.MaxFrames = n
+		}
+	}
+
+	return opt
+}
+
+func reportConfigErr(name string, err error) {
+	fmt.Fprintf(os.Stderr, "suplog: failed to parse %s: %v\n", name, err)
+}
+
+// openOutput opens the configured output target.
+func (cfg *Config) openOutput() (io.Writer, error) {
+	switch strings.ToLower(cfg.Output) {
+	case "", "stderr":
+		return os.Stderr, nil
+	case "stdout":
+		return os.Stdout, nil
+	}
+
+	f, err := file.New(cfg.Output, cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log output: %w", err)
+	}
+
+	return f, nil
+}
+
+// openOutputOrStderr opens the configured output target, falling back
+// to stderr in case of failure.
+func (cfg *Config) openOutputOrStderr() io.Writer {
+	wr, err := cfg.openOutput()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "suplog: %v, using stderr\n", err)
+		return os.Stderr
+	}
+
+	return wr
+}
+
+// newFormatter constructs the configured formatter.
+func (cfg *Config) newFormatter() Formatter {
+	switch strings.ToLower(cfg.Formatter) {
+	case "pretty":
+		return &PrettyFormatter{
+			TimestampFormat: cfg.TimestampFormat,
+		}
+	case "cli":
+		return &PrettyFormatter{
+			CLIMode: true,
+		}
+	case "json":
+		return &JSONFormatter{
+			TimestampFormat: cfg.TimestampFormat,
+		}
+	case "logfmt":
+		return &LogfmtFormatter{
+			TimestampFormat: cfg.TimestampFormat,
+		}
+	case "ecs":
+		return &ECSFormatter{}
+	case "gelf":
+		return &GELFFormatter{}
+	case "gcp":
+		return &GCPFormatter{
+			ProjectID: os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		}
+	default:
+		formatter := &TextFormatter{
+			TimestampFormat: cfg.TimestampFormat,
+			FullTimestamp:   len(cfg.TimestampFormat) > 0,
+		}
+
+		if cfg.StackHook != nil {
+			return stackHook.NewTextFormatter(formatter)
+		}
+
+		return formatter
+	}
+}
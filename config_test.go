@@ -0,0 +1,132 @@
+package suplog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	debugHook "github.com/xlab/suplog/hooks/debug"
+	"github.com/xlab/suplog/sampler"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	setenv(t, "LOG_LEVEL", "warn")
+	setenv(t, "LOG_LEVELS", "db=debug")
+	setenv(t, "LOG_FORMATTER", "json")
+	setenv(t, "LOG_DEBUG_LEVELS", "debug,warn")
+
+	cfg := ConfigFromEnv()
+
+	if cfg.Level != WarnLevel {
+		t.Errorf("expected warn level, got %s", cfg.Level)
+	}
+
+	if cfg.Levels["db"] != DebugLevel {
+		t.Errorf("expected debug level for db, got %v", cfg.Levels)
+	}
+
+	if _, ok := cfg.newFormatter().(*JSONFormatter); !ok {
+		t.Errorf("expected JSON formatter, got %T", cfg.newFormatter())
+	}
+
+	if cfg.DebugHook == nil || len(cfg.DebugHook.Levels) != 2 {
+		t.Errorf("expected debug hook enabled for two levels, got %+v", cfg.DebugHook)
+	}
+}
+
+func TestNewLoggerWithConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+
+	logger, err := NewLoggerWithConfig(&Config{
+		Level:     InfoLevel,
+		Output:    path,
+		Formatter: "json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Debug("not logged")
+	logger.Error("logged")
+	logger.(*suplogger).Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "not logged") || !strings.Contains(string(data), `"msg":"logged"`) {
+		t.Errorf("unexpected output: %s", data)
+	}
+}
+
+func TestNewLoggerConfigFromEnv(t *testing.T) {
+	setenv(t, "LOG_LEVEL", "warn")
+	setenv(t, "LOG_LEVELS", "db=debug")
+
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, new(JSONFormatter))
+
+	logger.Info("not logged")
+	logger.Named("db").Debug("logged")
+
+	if strings.Contains(out.String(), "not logged") || !strings.Contains(out.String(), `"msg":"logged"`) {
+		t.Errorf("expected levels from env, got %q", out.String())
+	}
+}
+
+func TestConfigLevelUnset(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger, err := NewLoggerWithConfig(&Config{Formatter: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.(LoggerConfigurator).SetOutput(out)
+	logger.Debug("logged")
+
+	if !strings.Contains(out.String(), `"msg":"logged"`) {
+		t.Errorf("expected debug level by default, got %q", out.String())
+	}
+}
+
+func TestHookLoggerConfig(t *testing.T) {
+	logger, err := NewLoggerWithConfig(&Config{
+		Level:     InfoLevel,
+		Sampling:  &sampler.Options{},
+		Dedup:     &DedupOptions{},
+		DebugHook: &debugHook.HookOptions{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hookLogger := logger.(*suplogger).newHookLogger(&Config{
+		Level:    InfoLevel,
+		Sampling: &sampler.Options{},
+		Dedup:    &DedupOptions{},
+	})
+
+	if hookLogger.sampler != nil || hookLogger.dedup != nil || len(hookLogger.logger.Hooks) > 0 {
+		t.Error("expected hook logger without sampling, dedup and hooks")
+	}
+
+	if hookLogger.GetLevel() != InfoLevel {
+		t.Errorf("expected level of the parent config, got %s", hookLogger.GetLevel())
+	}
+}
+
+func setenv(t *testing.T, key, value string) {
+	prev, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package suplog
+
+import (
+	"context"
+)
+
+type contextFieldsKey struct{}
+
+// ContextWithFields returns a copy of ctx carrying fields, merged with fields
+// already accumulated on ctx. Entries logged with that ctx get all these fields,
+// so middleware can attach e.g. request_id once for all downstream log lines.
+func ContextWithFields(ctx context.Context, fields Fields) context.Context {
+	return context.WithValue(ctx, contextFieldsKey{}, WithMore(FieldsFromContext(ctx), fields))
+}
+
+// FieldsFromContext returns fields accumulated on ctx by ContextWithFields.
+func FieldsFromContext(ctx context.Context) Fields {
+	if ctx == nil {
+		return nil
+	}
+
+	fields, _ := ctx.Value(contextFieldsKey{}).(Fields)
+
+	return fields
+}
@@ -0,0 +1,34 @@
+package suplog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestContextWithFields(t *testing.T) {
+	ctx := ContextWithFields(context.Background(), Fields{
+		"request_id": "r1",
+		"user_id":    "u1",
+	})
+	ctx = ContextWithFields(ctx, Fields{
+		"user_id": "u2",
+	})
+
+	fields := FieldsFromContext(ctx)
+	if len(fields) != 2 || fields["request_id"] != "r1" || fields["user_id"] != "u2" {
+		t.Errorf("unexpected fields accumulated on context: %v", fields)
+	}
+
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, new(JSONFormatter))
+
+	logger.WithField("user_id", "explicit").WithContext(ctx).Info("handled")
+
+	for _, field := range []string{`"request_id":"r1"`, `"user_id":"explicit"`} {
+		if !strings.Contains(out.String(), field) {
+			t.Errorf("expected entry with %s, got %s", field, out.String())
+		}
+	}
+}
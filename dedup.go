@@ -0,0 +1,176 @@
+package suplog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Field names of the summary entry logged after a run of duplicates.
+const (
+	RepeatedField  = "repeated"
+	FirstSeenField = "first_seen"
+	LastSeenField  = "last_seen"
+)
+
+// DedupOptions allows to set duplicate suppression options.
+type DedupOptions struct {
+	// Window is the maximum duration of a run of duplicates, after that
+	// the summary is logged and the next duplicate is logged as usual.
+	Window time.Duration
+}
+
+const defaultDedupWindow = 10 * time.Second
+
+func checkDedupOptions(opt *DedupOptions) *DedupOptions {
+	if opt == nil {
+		opt = &DedupOptions{}
+	}
+
+	if opt.Window <= 0 {
+		opt.Window = defaultDedupWindow
+	}
+
+	return opt
+}
+
+// deduper collapses consecutive entries with the same level, message and fields.
+// The first entry of a run is logged, the rest are counted and summarized
+// by a single entry with repeated, first_seen and last_seen fields.
+type deduper struct {
+	opt *DedupOptions
+
+	// withCaller pins the call site into the entry, as summaries are logged later
+	withCaller func(entry *logrus.Entry) *logrus.Entry
+
+	mux   sync.Mutex
+	run   *dedupRun
+	timer *time.Timer
+}
+
+type dedupRun struct {
+	key       string
+	entry     *logrus.Entry
+	level     Level
+	msg       string
+	repeated  int
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+func newDeduper(opt *DedupOptions, withCaller func(entry *logrus.Entry) *logrus.Entry) *deduper {
+	return &deduper{
+		opt:        checkDedupOptions(opt),
+		withCaller: withCaller,
+	}
+}
+
+// check reports whether the entry should be logged, logging the summary
+// of the previous run first, if it has ended. Fatal and Panic entries
+// are never suppressed.
+func (d *deduper) check(entry *logrus.Entry, level Level, msg string) bool {
+	key := dedupKey(entry, level, msg)
+	now := time.Now()
+
+	d.mux.Lock()
+	if level > FatalLevel && d.run != nil && d.run.key == key && now.Sub(d.run.firstSeen) < d.opt.Window {
+		if d.run.repeated == 0 && d.withCaller != nil {
+			d.run.entry = d.withCaller(d.run.entry)
+		}
+
+		d.run.repeated++
+		d.run.lastSeen = now
+		d.mux.Unlock()
+
+		return false
+	}
+
+	prevRun := d.run
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+
+	run := &dedupRun{
+		key:       key,
+		entry:     entry,
+		level:     level,
+		msg:       msg,
+		firstSeen: now,
+		lastSeen:  now,
+	}
+
+	d.run = run
+	d.timer = time.AfterFunc(d.opt.Window, func() {
+		d.expire(run)
+	})
+	d.mux.Unlock()
+
+	prevRun.summarize()
+
+	return true
+}
+
+// expire ends the run once its window is over.
+func (d *deduper) expire(run *dedupRun) {
+	d.mux.Lock()
+	if d.run != run {
+		d.mux.Unlock()
+		return
+	}
+
+	d.run = nil
+	d.mux.Unlock()
+
+	run.summarize()
+}
+
+// flush ends the current run, logging its summary.
+func (d *deduper) flush() {
+	d.mux.Lock()
+	run := d.run
+	d.run = nil
+
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.mux.Unlock()
+
+	run.summarize()
+}
+
+// summarize logs the summary entry, if there were any duplicates.
+func (r *dedupRun) summarize() {
+	if r == nil || r.repeated == 0 {
+		return
+	}
+
+	r.entry.WithFields(Fields{
+		RepeatedField:  r.repeated,
+		FirstSeenField: r.firstSeen,
+		LastSeenField:  r.lastSeen,
+	}).Log(r.level, r.msg)
+}
+
+// dedupKey returns a fingerprint of the entry level, message and fields.
+func dedupKey(entry *logrus.Entry, level Level, msg string) string {
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d|%s", level, msg)
+
+	for _, k := range keys {
+		fmt.Fprintf(&b, "|%s=%v", k, entry.Data[k])
+	}
+
+	return b.String()
+}
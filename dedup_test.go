@@ -0,0 +1,115 @@
+package suplog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe to write from the dedup timer.
+type syncBuffer struct {
+	mux sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.buf.String()
+}
+
+func newDedupLogger(t *testing.T, window time.Duration) (Logger, *syncBuffer) {
+	out := new(syncBuffer)
+	logger, err := NewLoggerWithConfig(&Config{
+		Level:     DebugLevel,
+		Formatter: "json",
+		Dedup: &DedupOptions{
+			Window: window,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.(LoggerConfigurator).SetOutput(out)
+
+	return logger, out
+}
+
+func TestDedupRunEnds(t *testing.T) {
+	logger, out := newDedupLogger(t, time.Hour)
+
+	for i := 0; i < 5; i++ {
+		logger.WithField("host", "db").Error("connection refused")
+	}
+
+	logger.WithField("host", "cache").Error("connection refused")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got: %s", out.String())
+	}
+
+	if strings.Contains(lines[0], RepeatedField) {
+		t.Errorf("expected first entry without summary fields, got %s", lines[0])
+	}
+
+	for _, field := range []string{`"repeated":4`, `"first_seen":`, `"last_seen":`, `"host":"db"`} {
+		if !strings.Contains(lines[1], field) {
+			t.Errorf("expected summary entry with %s, got %s", field, lines[1])
+		}
+	}
+
+	if !strings.Contains(lines[2], `"host":"cache"`) {
+		t.Errorf("expected entry with other fields, got %s", lines[2])
+	}
+}
+
+func TestDedupWindowExpires(t *testing.T) {
+	logger, out := newDedupLogger(t, 50*time.Millisecond)
+
+	logger.Warning("disk is full")
+	logger.Warning("disk is full")
+	logger.Warning("disk is full")
+
+	time.Sleep(200 * time.Millisecond)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got: %s", out.String())
+	}
+
+	if !strings.Contains(lines[1], `"repeated":2`) {
+		t.Errorf("expected summary entry, got %s", lines[1])
+	}
+
+	logger.Warning("disk is full")
+
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || strings.Contains(lines[2], RepeatedField) {
+		t.Errorf("expected entry to be logged after window, got: %s", out.String())
+	}
+}
+
+func TestDedupFlushOnClose(t *testing.T) {
+	logger, out := newDedupLogger(t, time.Hour)
+
+	logger.Info("retrying")
+	logger.Info("retrying")
+
+	logger.(interface{ Close() error }).Close()
+
+	if !strings.Contains(out.String(), `"repeated":1`) {
+		t.Errorf("expected summary entry on close, got: %s", out.String())
+	}
+}
@@ -73,10 +73,30 @@ func WithContext(ctx context.Context) Logger {
 	return DefaultLogger.WithContext(ctx)
 }
 
+// FromContext returns the default logger with fields accumulated on ctx
+// by ContextWithFields, the ctx is passed along with entries.
+func FromContext(ctx context.Context) Logger {
+	return DefaultLogger.WithContext(ctx)
+}
+
 func WithTime(t time.Time) Logger {
 	return DefaultLogger.WithTime(t)
 }
 
+// WithStack forces the stack hook to attach the stack to the log entry.
+func WithStack() Logger {
+	return DefaultLogger.WithStack()
+}
+
+// With adds typed fields to the default logger, see Field.
+func With(fields ...Field) Logger {
+	return DefaultLogger.With(fields...)
+}
+
+func Named(name string) Logger {
+	return DefaultLogger.Named(name)
+}
+
 // Part B: Formatted logging methods
 
 func Logf(level Level, format string, args ...interface{}) {
@@ -0,0 +1,70 @@
+package suplog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	stackHook "github.com/xlab/suplog/hooks/stack"
+)
+
+const (
+	ecsVersion         = "1.6.0"
+	ecsTimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// ECSFormatter formats entries as Elastic Common Schema JSON lines.
+// The logger name, errors, stacks and fn/src of the debug hook are mapped
+// onto log.logger, error.* and log.origin.* fields, other fields are kept
+// at the top level as custom fields.
+type ECSFormatter struct {
+	// ServiceName sets service.name of each entry, omitted when empty.
+	ServiceName string
+}
+
+// Format renders a single log entry.
+func (f *ECSFormatter) Format(e *Entry) ([]byte, error) {
+	data := make(map[string]interface{}, len(e.Data)+4)
+
+	for k, v := range e.Data {
+		switch k {
+		case LoggerField:
+			data["log.logger"] = v
+		case logrus.ErrorKey:
+			data["error.message"] = fmt.Sprint(jsonValue(v))
+			if err, ok := v.(error); ok {
+				data["error.type"] = fmt.Sprintf("%T", err)
+			}
+		case stackHook.Field:
+			if trace, ok := stackTrace(v); ok {
+				data["error.stack_trace"] = trace
+			} else {
+				data[k] = v
+			}
+		case fnField:
+			data["log.origin.function"] = v
+		case srcField:
+			if file, line, ok := parseSrc(v); ok {
+				data["log.origin.file.name"] = file
+				if line > 0 {
+					data["log.origin.file.line"] = line
+				}
+			} else {
+				data[k] = v
+			}
+		default:
+			data[k] = jsonValue(v)
+		}
+	}
+
+	data["@timestamp"] = e.Time.UTC().Format(ecsTimestampFormat)
+	data["log.level"] = e.Level.String()
+	data["message"] = strings.TrimSuffix(e.Message, "\n")
+	data["ecs.version"] = ecsVersion
+
+	if len(f.ServiceName) > 0 {
+		data["service.name"] = f.ServiceName
+	}
+
+	return encodeJSON(e, data)
+}
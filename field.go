@@ -0,0 +1,165 @@
+package suplog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fieldKind uint8
+
+const (
+	anyKind fieldKind = iota
+	stringKind
+	intKind
+	durationKind
+)
+
+// Field is a typed log field, constructed by String, Int, Dur, Err and Any.
+// Unlike Fields, typed fields passed to With are kept unboxed and are merged
+// into the entry only when it is actually logged.
+type Field struct {
+	Key string
+
+	kind  fieldKind
+	num   int64
+	str   string
+	iface interface{}
+}
+
+// String constructs a field with the string value.
+func String(key, value string) Field {
+	return Field{
+		Key:  key,
+		kind: stringKind,
+		str:  value,
+	}
+}
+
+// Int constructs a field with the int value.
+func Int(key string, value int) Field {
+	return Field{
+		Key:  key,
+		kind: intKind,
+		num:  int64(value),
+	}
+}
+
+// Dur constructs a field with the time.Duration value.
+func Dur(key string, value time.Duration) Field {
+	return Field{
+		Key:  key,
+		kind: durationKind,
+		num:  int64(value),
+	}
+}
+
+// Err constructs a field with the error, using the same key as WithError.
+func Err(err error) Field {
+	return Field{
+		Key:   logrus.ErrorKey,
+		kind:  anyKind,
+		iface: err,
+	}
+}
+
+// Any constructs a field with an arbitrary value.
+func Any(key string, value interface{}) Field {
+	return Field{
+		Key:   key,
+		kind:  anyKind,
+		iface: value,
+	}
+}
+
+// Value returns the field value.
+func (f Field) Value() interface{} {
+	switch f.kind {
+	case stringKind:
+		return f.str
+	case intKind:
+		return int(f.num)
+	case durationKind:
+		return time.Duration(f.num)
+	default:
+		return f.iface
+	}
+}
+
+// appendFields returns fields followed by more, never sharing
+// the backing array with fields, so derived loggers don't interfere.
+func appendFields(fields []Field, more []Field) []Field {
+	out := make([]Field, 0, len(fields)+len(more))
+	out = append(out, fields...)
+
+	return append(out, more...)
+}
+
+// inlineFields is the number of typed fields allocated along with the logger copy.
+const inlineFields = 4
+
+// inlineFieldsLogger is the logger copy made by With, allocated along with its fields.
+type inlineFieldsLogger struct {
+	suplogger
+	fields [inlineFields]Field
+}
+
+// entryPool keeps entries with typed fields written into their data. Pooled entries
+// never reach formatters and hooks, as logrus passes them a copy of the entry.
+var entryPool = sync.Pool{
+	New: func() interface{} {
+		return &logrus.Entry{
+			Data: make(Fields),
+		}
+	},
+}
+
+// getEntry returns a pooled copy of the entry with typed fields written
+// straight into its data, release it with putEntry once logged.
+func getEntry(entry *logrus.Entry, fields []Field) *logrus.Entry {
+	pooled := entryPool.Get().(*logrus.Entry)
+	pooled.Logger = entry.Logger
+	pooled.Time = entry.Time
+	pooled.Context = entry.Context
+
+	for k, v := range entry.Data {
+		pooled.Data[k] = v
+	}
+
+	for _, f := range fields {
+		pooled.Data[f.Key] = f.Value()
+	}
+
+	return pooled
+}
+
+func putEntry(entry *logrus.Entry) {
+	for k := range entry.Data {
+		delete(entry.Data, k)
+	}
+
+	entry.Logger = nil
+	entry.Context = nil
+	entryPool.Put(entry)
+}
+
+// entryWithFields returns a copy of the entry with typed fields merged in,
+// skipping reflection based checks of logrus.Entry.WithFields.
+func entryWithFields(entry *logrus.Entry, fields []Field) *logrus.Entry {
+	data := make(Fields, len(entry.Data)+len(fields))
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+
+	for _, f := range fields {
+		data[f.Key] = f.Value()
+	}
+
+	return &logrus.Entry{
+		Logger:  entry.Logger,
+		Data:    data,
+		Time:    entry.Time,
+		Context: entry.Context,
+	}
+}
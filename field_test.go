@@ -0,0 +1,163 @@
+package suplog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithTypedFields(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, new(JSONFormatter))
+
+	base := logger.With(String("user", "max"), Int("attempt", 3))
+	base.With(Dur("took", 1500*time.Millisecond), Err(errors.New("timeout"))).Info("request failed")
+	base.With(String("user", "john")).WithField("attempt", 4).Info("request retried")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got: %s", out.String())
+	}
+
+	for _, field := range []string{`"user":"max"`, `"attempt":3`, `"took":1500000000`, `"error":"timeout"`} {
+		if !strings.Contains(lines[0], field) {
+			t.Errorf("expected entry with %s, got %s", field, lines[0])
+		}
+	}
+
+	for _, field := range []string{`"user":"john"`, `"attempt":4`} {
+		if !strings.Contains(lines[1], field) {
+			t.Errorf("expected entry with %s, got %s", field, lines[1])
+		}
+	}
+}
+
+func TestWithReusedEntries(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, new(JSONFormatter))
+
+	logger.With(String("user", "max")).Info("first")
+	logger.With(Int("attempt", 2)).Info("second")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || strings.Contains(lines[1], `"user"`) || !strings.Contains(lines[1], `"attempt":2`) {
+		t.Errorf("expected fields of the first entry not kept, got %s", out.String())
+	}
+}
+
+func newBenchLogger(level Level) Logger {
+	logger := NewLogger(io.Discard, new(JSONFormatter))
+	logger.(LoggerConfigurator).SetLevel(level)
+
+	return logger
+}
+
+func BenchmarkWithFields(b *testing.B) {
+	logger := newBenchLogger(InfoLevel)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		logger.WithFields(Fields{
+			"user":    "max",
+			"attempt": i,
+			"took":    time.Second,
+		}).Info("request done")
+	}
+}
+
+func BenchmarkWith(b *testing.B) {
+	logger := newBenchLogger(InfoLevel)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		logger.With(
+			String("user", "max"),
+			Int("attempt", i),
+			Dur("took", time.Second),
+		).Info("request done")
+	}
+}
+
+func BenchmarkWithFieldsDisabled(b *testing.B) {
+	logger := newBenchLogger(InfoLevel)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		logger.WithFields(Fields{
+			"user":    "max",
+			"attempt": i,
+			"took":    time.Second,
+		}).Debug("request done")
+	}
+}
+
+func BenchmarkWithDisabled(b *testing.B) {
+	logger := newBenchLogger(InfoLevel)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		logger.With(
+			String("user", "max"),
+			Int("attempt", i),
+			Dur("took", time.Second),
+		).Debug("request done")
+	}
+}
+
+func BenchmarkWithDisabledGuarded(b *testing.B) {
+	logger := newBenchLogger(InfoLevel)
+	levels := logger.(LoggerConfigurator)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if levels.IsLevelEnabled(DebugLevel) {
+			logger.With(
+				String("user", "max"),
+				Int("attempt", i),
+				Dur("took", time.Second),
+			).Debug("request done")
+		}
+	}
+
+	b.StopTimer()
+
+	if allocs := testing.AllocsPerRun(100, func() {
+		if levels.IsLevelEnabled(DebugLevel) {
+			logger.With(String("user", "max")).Debug("request done")
+		}
+	}); allocs != 0 {
+		b.Fatalf("expected no allocations on the disabled path, got %v", allocs)
+	}
+}
+
+func TestWithDisabledAllocs(t *testing.T) {
+	logger := newBenchLogger(InfoLevel).With(
+		String("user", "max"),
+		Int("attempt", 1),
+		Dur("took", time.Second),
+	)
+
+	if allocs := testing.AllocsPerRun(100, func() {
+		logger.Debug("request done")
+	}); allocs != 0 {
+		t.Errorf("expected no allocations logging on a disabled level, got %v", allocs)
+	}
+
+	levels := logger.(LoggerConfigurator)
+
+	if allocs := testing.AllocsPerRun(100, func() {
+		if levels.IsLevelEnabled(DebugLevel) {
+			logger.With(String("user", "john")).Debug("request done")
+		}
+	}); allocs != 0 {
+		t.Errorf("expected no allocations on the guarded disabled path, got %v", allocs)
+	}
+}
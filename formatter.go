@@ -0,0 +1,98 @@
+package suplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	stackHook "github.com/xlab/suplog/hooks/stack"
+)
+
+// Field names added by the debug hook, used by formatters for source locations.
+const (
+	fnField  = "fn"
+	srcField = "src"
+)
+
+// syslogSeverity maps levels onto syslog severities (RFC 5424), as used by GELF and syslog.
+func syslogSeverity(level Level) int {
+	switch level {
+	case PanicLevel:
+		return 1 // alert
+	case FatalLevel:
+		return 2 // critical
+	case ErrorLevel:
+		return 3 // error
+	case WarnLevel:
+		return 4 // warning
+	case InfoLevel:
+		return 6 // informational
+	default:
+		return 7 // debug
+	}
+}
+
+// parseSrc splits the src field of the debug hook into file and line.
+func parseSrc(src interface{}) (file string, line int, ok bool) {
+	s, isString := src.(string)
+	if !isString {
+		return "", 0, false
+	}
+
+	idx := strings.LastIndexByte(s, ':')
+	if idx < 0 {
+		return s, 0, len(s) > 0
+	}
+
+	line, err := strconv.Atoi(s[idx+1:])
+	if err != nil {
+		return s, 0, true
+	}
+
+	return s[:idx], line, true
+}
+
+// jsonValue prepares the field value for JSON encoding, errors are
+// encoded as their messages, like logrus.JSONFormatter does.
+func jsonValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+
+	return v
+}
+
+// encodeJSON writes the object as a JSON line into the entry buffer.
+func encodeJSON(e *Entry, obj interface{}) ([]byte, error) {
+	b := e.Buffer
+	if b == nil {
+		b = new(bytes.Buffer)
+	}
+
+	encoder := json.NewEncoder(b)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(obj); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// stackTrace renders frames of the stack hook as text, for formatters
+// with a dedicated stack trace field.
+func stackTrace(v interface{}) (string, bool) {
+	frames, ok := v.([]stackHook.Frame)
+	if !ok {
+		return "", false
+	}
+
+	var b strings.Builder
+	for _, frame := range frames {
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Func, frame.File, frame.Line)
+	}
+
+	return b.String(), true
+}
@@ -0,0 +1,216 @@
+package suplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	stackHook "github.com/xlab/suplog/hooks/stack"
+)
+
+func TestLogfmtFormatter(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, &LogfmtFormatter{
+		DisableTimestamp: true,
+	})
+
+	logger.WithFields(Fields{
+		"user":      "max",
+		"query":     "select 1",
+		"bad key=":  `say "hi"`,
+		"msg":       "clash",
+		"empty":     "",
+		"error":     errors.New("line one\nline two"),
+		"attempt":   3,
+		"something": nil,
+	}).Warning("query failed")
+
+	exp := `level=warning msg="query failed" attempt=3 bad_key_="say \"hi\"" empty="" ` +
+		`error="line one\nline two" fields.msg=clash query="select 1" something=null user=max` + "\n"
+
+	if out.String() != exp {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", out.String(), exp)
+	}
+}
+
+func TestLogfmtFormatterStack(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, &LogfmtFormatter{
+		DisableTimestamp: true,
+	})
+
+	logger.WithField(stackHook.Field, []stackHook.Frame{
+		{Func: "main.run", File: "app/run.go", Line: 20},
+		{Func: "main.main", File: "app/main.go", Line: 12},
+	}).Error("failed")
+
+	exp := `level=error msg=failed stack="main.run app/run.go:20; main.main app/main.go:12"` + "\n"
+
+	if out.String() != exp {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", out.String(), exp)
+	}
+}
+
+func TestWithStackWithoutHook(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, &LogfmtFormatter{
+		DisableTimestamp: true,
+	})
+
+	logger.WithStack().Info("no stack hook")
+
+	if exp := "level=info msg=\"no stack hook\"\n"; out.String() != exp {
+		t.Errorf("expected no stack marker in output, got %q", out.String())
+	}
+}
+
+func formatJSON(t *testing.T, formatter Formatter, fn func(logger Logger)) map[string]interface{} {
+	out := new(bytes.Buffer)
+	fn(NewLogger(out, formatter))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode %s: %v", out.String(), err)
+	}
+
+	return entry
+}
+
+func TestECSFormatter(t *testing.T) {
+	entry := formatJSON(t, &ECSFormatter{ServiceName: "api"}, func(logger Logger) {
+		logger.Named("db").WithFields(Fields{
+			"user":  "max",
+			"src":   "app/repo/repo.go:42",
+			"fn":    "Find",
+			"error": errors.New("timeout"),
+		}).Error("query failed")
+	})
+
+	exp := map[string]interface{}{
+		"log.level":            "error",
+		"message":              "query failed",
+		"ecs.version":          ecsVersion,
+		"service.name":         "api",
+		"log.logger":           "db",
+		"error.message":        "timeout",
+		"error.type":           "*errors.errorString",
+		"log.origin.function":  "Find",
+		"log.origin.file.name": "app/repo/repo.go",
+		"log.origin.file.line": float64(42),
+		"user":                 "max",
+	}
+
+	for k, v := range exp {
+		if entry[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, entry[k])
+		}
+	}
+
+	if _, ok := entry["@timestamp"]; !ok {
+		t.Errorf("expected @timestamp, got %v", entry)
+	}
+}
+
+func TestGELFFormatter(t *testing.T) {
+	entry := formatJSON(t, &GELFFormatter{Host: "node-1"}, func(logger Logger) {
+		logger.WithFields(Fields{
+			"id":      "42",
+			"user id": "max",
+			"attempt": 3,
+			"retry":   true,
+		}).Warning("query failed\nselect 1")
+	})
+
+	exp := map[string]interface{}{
+		"version":       "1.1",
+		"host":          "node-1",
+		"short_message": "query failed",
+		"full_message":  "query failed\nselect 1",
+		"level":         float64(4),
+		"_id_":          "42",
+		"_user_id":      "max",
+		"_attempt":      float64(3),
+		"_retry":        "true",
+	}
+
+	for k, v := range exp {
+		if entry[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, entry[k])
+		}
+	}
+
+	if _, ok := entry["timestamp"].(float64); !ok {
+		t.Errorf("expected numeric timestamp, got %v", entry["timestamp"])
+	}
+}
+
+func TestGCPFormatter(t *testing.T) {
+	entry := formatJSON(t, &GCPFormatter{ProjectID: "acme"}, func(logger Logger) {
+		logger.WithFields(Fields{
+			"src":         "app/repo/repo.go:42",
+			"fn":          "Find",
+			"trace_id":    "4bf92f3577b34da6a3ce929d0e0e4736",
+			"span_id":     "00f067aa0ba902b7",
+			"trace_flags": "01",
+		}).Error("query failed")
+	})
+
+	exp := map[string]interface{}{
+		"severity":                             "ERROR",
+		"message":                              "query failed",
+		"logging.googleapis.com/trace":         "projects/acme/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		"logging.googleapis.com/spanId":        "00f067aa0ba902b7",
+		"logging.googleapis.com/trace_sampled": true,
+	}
+
+	for k, v := range exp {
+		if entry[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, entry[k])
+		}
+	}
+
+	location, _ := entry["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	if location["file"] != "app/repo/repo.go" || location["line"] != "42" || location["function"] != "Find" {
+		t.Errorf("unexpected source location: %v", location)
+	}
+
+	for _, k := range []string{"src", "fn", "trace_id"} {
+		if _, ok := entry[k]; ok {
+			t.Errorf("expected %s to be mapped, got %v", k, entry)
+		}
+	}
+
+	notice := formatJSON(t, &GCPFormatter{}, func(logger Logger) {
+		logger.(*suplogger).Notification("new version available")
+	})
+
+	if notice["severity"] != "NOTICE" {
+		t.Errorf("expected NOTICE severity, got %v", notice["severity"])
+	}
+
+	if severity := gcpSeverity(&Entry{Level: FatalLevel}); severity != "CRITICAL" {
+		t.Errorf("expected CRITICAL severity for Fatal, got %s", severity)
+	}
+}
+
+func TestFormatterFromEnv(t *testing.T) {
+	setenv(t, "GOOGLE_CLOUD_PROJECT", "acme")
+
+	for name, exp := range map[string]Formatter{
+		"logfmt": &LogfmtFormatter{},
+		"ecs":    &ECSFormatter{},
+		"gelf":   &GELFFormatter{},
+		"gcp":    &GCPFormatter{ProjectID: "acme"},
+	} {
+		formatter := (&Config{Formatter: name}).newFormatter()
+		if fmt.Sprintf("%T", formatter) != fmt.Sprintf("%T", exp) {
+			t.Errorf("expected %T for %s, got %T", exp, name, formatter)
+		}
+	}
+
+	if formatter := (&Config{Formatter: "gcp"}).newFormatter().(*GCPFormatter); formatter.ProjectID != "acme" {
+		t.Errorf("expected project from GOOGLE_CLOUD_PROJECT, got %q", formatter.ProjectID)
+	}
+}
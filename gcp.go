@@ -0,0 +1,116 @@
+package suplog
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Special fields recognized by Google Cloud Logging in structured JSON payloads.
+const (
+	gcpSourceLocationField = "logging.googleapis.com/sourceLocation"
+	gcpTraceField          = "logging.googleapis.com/trace"
+	gcpSpanIDField         = "logging.googleapis.com/spanId"
+	gcpTraceSampledField   = "logging.googleapis.com/trace_sampled"
+)
+
+// Field names added by the OpenTelemetry hook.
+const (
+	otelTraceIDField    = "trace_id"
+	otelSpanIDField     = "span_id"
+	otelTraceFlagsField = "trace_flags"
+)
+
+// GCPFormatter formats entries as Google Cloud structured logging JSON lines,
+// as read by the logging agent on GKE, Cloud Run and App Engine. Levels are
+// mapped onto severities, Notification entries are logged with NOTICE severity.
+// The fn and src fields of the debug hook become the source location, trace
+// and span IDs of the OpenTelemetry hook are correlated when ProjectID is set.
+type GCPFormatter struct {
+	// ProjectID enables trace correlation, trace_id is converted
+	// to projects/PROJECT_ID/traces/TRACE_ID when set.
+	ProjectID string
+}
+
+type gcpSourceLocation struct {
+	File     string `json:"file,omitempty"`
+	Line     string `json:"line,omitempty"`
+	Function string `json:"function,omitempty"`
+}
+
+// Format renders a single log entry.
+func (f *GCPFormatter) Format(e *Entry) ([]byte, error) {
+	data := make(map[string]interface{}, len(e.Data)+4)
+
+	var location gcpSourceLocation
+
+	for k, v := range e.Data {
+		switch k {
+		case fnField:
+			if fn, ok := v.(string); ok {
+				location.Function = fn
+				continue
+			}
+		case srcField:
+			if file, line, ok := parseSrc(v); ok {
+				location.File = file
+				if line > 0 {
+					location.Line = strconv.Itoa(line)
+				}
+
+				continue
+			}
+		case otelTraceIDField:
+			if traceID, ok := v.(string); ok && len(f.ProjectID) > 0 {
+				data[gcpTraceField] = "projects/" + f.ProjectID + "/traces/" + traceID
+				continue
+			}
+		case otelSpanIDField:
+			if len(f.ProjectID) > 0 {
+				data[gcpSpanIDField] = v
+				continue
+			}
+		case otelTraceFlagsField:
+			if flags, ok := v.(string); ok && len(f.ProjectID) > 0 {
+				if n, err := strconv.ParseUint(flags, 16, 8); err == nil {
+					data[gcpTraceSampledField] = n&1 == 1
+					continue
+				}
+			}
+		}
+
+		data[k] = jsonValue(v)
+	}
+
+	if location != (gcpSourceLocation{}) {
+		data[gcpSourceLocationField] = location
+	}
+
+	data["severity"] = gcpSeverity(e)
+	data["message"] = strings.TrimSuffix(e.Message, "\n")
+	data["time"] = e.Time.UTC().Format(time.RFC3339Nano)
+
+	return encodeJSON(e, data)
+}
+
+// gcpSeverity maps the entry level onto Cloud Logging LogSeverity.
+func gcpSeverity(e *Entry) string {
+	switch e.Level {
+	case PanicLevel:
+		return "ALERT"
+	case FatalLevel:
+		return "CRITICAL"
+	case ErrorLevel:
+		return "ERROR"
+	case WarnLevel:
+		return "WARNING"
+	case InfoLevel:
+		if kind, _ := e.Data[KindField].(string); kind == KindNotification {
+			return "NOTICE"
+		}
+
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
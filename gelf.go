@@ -0,0 +1,104 @@
+package suplog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const gelfVersion = "1.1"
+
+// GELFFormatter formats entries as GELF 1.1 JSON lines for Graylog. The first line
+// of the message becomes short_message, multi-line messages are kept in full_message.
+// Fields are sent as additional fields prefixed with '_', values other than
+// strings and numbers are converted to strings, as GELF requires.
+type GELFFormatter struct {
+	// Host sets the host field, os.Hostname by default.
+	Host string
+
+	hostOnce sync.Once
+	host     string
+}
+
+// Format renders a single log entry.
+func (f *GELFFormatter) Format(e *Entry) ([]byte, error) {
+	msg := strings.TrimSuffix(e.Message, "\n")
+	shortMsg := msg
+	if idx := strings.IndexByte(msg, '\n'); idx >= 0 {
+		shortMsg = msg[:idx]
+	}
+
+	data := make(map[string]interface{}, len(e.Data)+6)
+
+	for k, v := range e.Data {
+		data[gelfKey(k)] = gelfValue(v)
+	}
+
+	data["version"] = gelfVersion
+	data["host"] = f.hostname()
+	data["short_message"] = shortMsg
+	data["timestamp"] = float64(e.Time.UnixNano()/1e3) / 1e6
+	data["level"] = syslogSeverity(e.Level)
+
+	if len(shortMsg) < len(msg) {
+		data["full_message"] = msg
+	}
+
+	return encodeJSON(e, data)
+}
+
+func (f *GELFFormatter) hostname() string {
+	if len(f.Host) > 0 {
+		return f.Host
+	}
+
+	f.hostOnce.Do(func() {
+		host, err := os.Hostname()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get hostname for GELF, %v\n", err)
+			host = "localhost"
+		}
+
+		f.host = host
+	})
+
+	return f.host
+}
+
+// gelfKey returns the additional field name, characters other than
+// letters, digits, '_', '.' and '-' are replaced with '_'.
+func gelfKey(k string) string {
+	if k == "id" {
+		// _id is reserved by GELF
+		return "_id_"
+	}
+
+	return "_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, k)
+}
+
+func gelfValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case error:
+		return v.Error()
+	}
+
+	if trace, ok := stackTrace(v); ok {
+		return trace
+	}
+
+	return fmt.Sprint(v)
+}
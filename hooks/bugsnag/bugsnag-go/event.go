@@ -3,6 +3,7 @@ package bugsnag
 import (
 	"context"
 	"net/http"
+	"reflect"
 	"strings"
 
 	"github.com/bugsnag/bugsnag-go/errors"
@@ -29,6 +30,25 @@ type ErrorClass struct {
 	Name string
 }
 
+// GroupingHash overrides the grouping of errors in Bugsnag, errors with
+// the same hash are grouped together. This can be passed as rawData.
+type GroupingHash struct {
+	Hash string
+}
+
+// Cause is an error in the chain of the reported error.
+type Cause struct {
+	// Err is the cause, its stack is sent if it implements errors.ErrorWithStackFrames
+	// or errors.ErrorWithCallers.
+	Err error
+	// ErrorClass overrides the error class, defaults to the type name of Err.
+	ErrorClass string
+}
+
+// Causes lists errors in the chain of the reported error, outermost first.
+// Each cause is sent as an additional exception. This can be passed as rawData.
+type Causes []Cause
+
 // Sets the severity of the error on Bugsnag. These values can be
 // passed to Notify, Recover or AutoNotify as rawData.
 var (
@@ -106,6 +126,8 @@ type Event struct {
 	Request *RequestJSON
 	// The reason for the severity and original value
 	handledState HandledState
+	// Exceptions for the causes of the error, sent after the error itself.
+	causes []exceptionJSON
 }
 
 func newEvent(rawData []interface{}, notifier *Notifier) (*Event, *Configuration) {
@@ -122,7 +144,10 @@ func newEvent(rawData []interface{}, notifier *Notifier) (*Event, *Configuration
 		},
 	}
 
-	var err *errors.Error
+	var (
+		err    *errors.Error
+		causes Causes
+	)
 
 	for _, datum := range event.RawData {
 		switch datum := datum.(type) {
@@ -135,7 +160,6 @@ func newEvent(rawData []interface{}, notifier *Notifier) (*Event, *Configuration
 				event.ErrorClass = err.TypeName()
 			}
 			event.Message = err.Error()
-			event.Stacktrace = make([]stackFrame, len(err.StackFrames()))
 
 		case bool:
 			config = config.merge(&Configuration{Synchronous: bool(datum)})
@@ -166,13 +190,50 @@ func newEvent(rawData []interface{}, notifier *Notifier) (*Event, *Configuration
 		case ErrorClass:
 			event.ErrorClass = datum.Name
 
+		case GroupingHash:
+			event.GroupingHash = datum.Hash
+
+		case Causes:
+			causes = datum
+
 		case HandledState:
 			event.handledState = datum
 			event.Severity = datum.OriginalSeverity
 		}
 	}
 
-	for i, frame := range err.StackFrames() {
+	event.Stacktrace = makeStacktrace(err.StackFrames(), config)
+
+	for _, cause := range causes {
+		if cause.Err == nil {
+			continue
+		}
+
+		exception := exceptionJSON{
+			ErrorClass: cause.ErrorClass,
+			Message:    cause.Err.Error(),
+			Stacktrace: []stackFrame{},
+		}
+
+		if len(exception.ErrorClass) == 0 {
+			exception.ErrorClass = reflect.TypeOf(cause.Err).String()
+		}
+
+		switch cause.Err.(type) {
+		case errors.ErrorWithStackFrames, errors.ErrorWithCallers:
+			exception.Stacktrace = makeStacktrace(errors.New(cause.Err, 0).StackFrames(), config)
+		}
+
+		event.causes = append(event.causes, exception)
+	}
+
+	return event, config
+}
+
+func makeStacktrace(frames []errors.StackFrame, config *Configuration) []stackFrame {
+	stacktrace := make([]stackFrame, len(frames))
+
+	for i, frame := range frames {
 		file := frame.File
 		inProject := config.isProjectPackage(frame.Package)
 
@@ -184,7 +245,7 @@ func newEvent(rawData []interface{}, notifier *Notifier) (*Event, *Configuration
 			file = config.stripProjectPackages(file)
 		}
 
-		event.Stacktrace[i] = stackFrame{
+		stacktrace[i] = stackFrame{
 			Method:     frame.Name,
 			File:       file,
 			LineNumber: frame.LineNumber,
@@ -192,7 +253,7 @@ func newEvent(rawData []interface{}, notifier *Notifier) (*Event, *Configuration
 		}
 	}
 
-	return event, config
+	return stacktrace
 }
 
 func populateEventWithContext(ctx context.Context, event *Event) {
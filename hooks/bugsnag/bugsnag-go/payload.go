@@ -77,13 +77,13 @@ func (p *payload) MarshalJSON() ([]byte, error) {
 					RuntimeVersions: device.GetRuntimeVersions(),
 				},
 				Request: p.Request,
-				Exceptions: []exceptionJSON{
+				Exceptions: append([]exceptionJSON{
 					exceptionJSON{
 						ErrorClass: p.ErrorClass,
 						Message:    p.Message,
 						Stacktrace: p.Stacktrace,
 					},
-				},
+				}, p.causes...),
 				GroupingHash:   p.GroupingHash,
 				Metadata:       p.MetaData.sanitize(p.ParamsFilters),
 				PayloadVersion: notifyPayloadVersion,
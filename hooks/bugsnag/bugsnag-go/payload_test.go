@@ -90,3 +90,34 @@ func makeLargePayload() *payload {
 	}
 	return &payload{&event, &config}
 }
+
+func TestMarshalCauses(t *testing.T) {
+	sessionTracker = sessions.NewSessionTracker(&sessionTrackingConfig)
+
+	rootCause := errors.Errorf("connection refused")
+	event, config := newEvent([]interface{}{
+		fmt.Errorf("query failed: %w", rootCause),
+		Causes{
+			{Err: rootCause.Err, ErrorClass: "net.OpError"},
+			{Err: rootCause},
+		},
+		GroupingHash{Hash: "root cause"},
+	}, New(Configuration{}))
+
+	bytes, err := (&payload{event, config}).MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(bytes)
+	for _, exp := range []string{
+		`"message":"query failed: connection refused"`,
+		`{"errorClass":"net.OpError","message":"connection refused","stacktrace":[]}`,
+		`{"errorClass":"*errors.Error","message":"connection refused","stacktrace":[{"method":"TestMarshalCauses"`,
+		`"groupingHash":"root cause"`,
+	} {
+		if !strings.Contains(got, exp) {
+			t.Errorf("Expected payload to contain\n'%s'\n but was\n'%s'", exp, got)
+		}
+	}
+}
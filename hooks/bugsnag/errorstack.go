@@ -3,10 +3,12 @@ package bugsnag
 import (
 	"fmt"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
 
+	bugsnag "github.com/bugsnag/bugsnag-go"
 	"github.com/bugsnag/bugsnag-go/errors"
 	pkgerrors "github.com/pkg/errors"
 
@@ -128,3 +130,128 @@ func newErrorWithPkgErrorsStackTrace(err error, stackTrace pkgerrors.StackTrace)
 
 	return e, nil
 }
+
+type multiUnwrapper interface {
+	Unwrap() []error
+}
+
+type unwrapper interface {
+	Unwrap() error
+}
+
+// errorChain returns the error followed by its causes, walking both Unwrap() error
+// and Unwrap() []error depth first. Wrappers that add neither a message nor a stack,
+// like pkg/errors withMessage below withStack, are skipped. Causes shared by joined
+// errors are listed once.
+func errorChain(err error) []error {
+	var (
+		chain []error
+		seen  = make(map[error]struct{})
+		walk  func(err error, parentMsg string)
+	)
+
+	walk = func(err error, parentMsg string) {
+		for err != nil {
+			if reflect.TypeOf(err).Comparable() {
+				if _, ok := seen[err]; ok {
+					return
+				}
+
+				seen[err] = struct{}{}
+			}
+
+			msg := err.Error()
+			if len(chain) == 0 || msg != parentMsg || hasStack(err) {
+				chain = append(chain, err)
+			}
+
+			parentMsg = msg
+
+			switch wrapped := err.(type) {
+			case multiUnwrapper:
+				for _, inner := range wrapped.Unwrap() {
+					walk(inner, parentMsg)
+				}
+
+				return
+			case unwrapper:
+				err = wrapped.Unwrap()
+			default:
+				return
+			}
+		}
+	}
+
+	walk(err, "")
+
+	return chain
+}
+
+// rootCause returns the innermost error, following the first error of joined ones.
+func rootCause(err error) error {
+	for {
+		switch wrapped := err.(type) {
+		case multiUnwrapper:
+			if errs := wrapped.Unwrap(); len(errs) > 0 && errs[0] != nil {
+				err = errs[0]
+				continue
+			}
+		case unwrapper:
+			if inner := wrapped.Unwrap(); inner != nil {
+				err = inner
+				continue
+			}
+		}
+
+		return err
+	}
+}
+
+func hasStack(err error) bool {
+	switch err.(type) {
+	case ErrorWithStackFrames, pkgErrorsStackTracer:
+		return true
+	}
+
+	return false
+}
+
+// newCause converts the error into a Bugsnag cause, parsing its pkg/errors stack if any.
+func newCause(err error) bugsnag.Cause {
+	cause := bugsnag.Cause{
+		Err:        err,
+		ErrorClass: fmt.Sprintf("%T", err),
+	}
+
+	if stackTracer, ok := err.(pkgErrorsStackTracer); ok {
+		if withStack, parseErr := newErrorWithPkgErrorsStackTrace(err, stackTracer.StackTrace()); parseErr == nil {
+			cause.Err = withStack
+		}
+	}
+
+	return cause
+}
+
+// groupingHash groups reports by the root cause type and the place where it
+// has been created. Messages are not hashed, as those often contain values,
+// so root causes without a stack are grouped by the call site of the report.
+func groupingHash(root error, callSite []runtime.Frame) string {
+	var frames []errors.StackFrame
+
+	switch withStack := root.(type) {
+	case ErrorWithStackFrames:
+		frames = withStack.StackFrames()
+	case pkgErrorsStackTracer:
+		if parsed, parseErr := newErrorWithPkgErrorsStackTrace(root, withStack.StackTrace()); parseErr == nil {
+			frames = parsed.StackFrames()
+		}
+	}
+
+	if len(frames) > 0 {
+		return fmt.Sprintf("%T@%s:%d", root, frames[0].File, frames[0].LineNumber)
+	} else if len(callSite) > 0 {
+		return fmt.Sprintf("%T@%s:%d", root, limitPath(callSite[0].File, 3), callSite[0].Line)
+	}
+
+	return fmt.Sprintf("%T", root)
+}
@@ -0,0 +1,122 @@
+package bugsnag
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// joinedErrors mimics errors.Join, which requires Go 1.20.
+type joinedErrors []error
+
+func (e joinedErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+func (e joinedErrors) Unwrap() []error {
+	return e
+}
+
+func newRefused(id int) error {
+	return pkgerrors.Errorf("connection %d refused", id)
+}
+
+func TestErrorChain(t *testing.T) {
+	base := errors.New("refused")
+
+	testCases := []struct {
+		name  string
+		err   error
+		chain []string
+		root  error
+	}{{
+		name:  "single",
+		err:   base,
+		chain: []string{"refused"},
+		root:  base,
+	}, {
+		name:  "fmt wrapped",
+		err:   fmt.Errorf("query: %w", fmt.Errorf("dial: %w", base)),
+		chain: []string{"query: dial: refused", "dial: refused", "refused"},
+		root:  base,
+	}, {
+		name:  "pkg/errors wrapped",
+		err:   pkgerrors.Wrap(pkgerrors.Wrap(base, "dial"), "query"),
+		chain: []string{"query: dial: refused", "dial: refused", "refused"},
+		root:  base,
+	}, {
+		name:  "mixed",
+		err:   fmt.Errorf("query: %w", pkgerrors.Wrap(base, "dial")),
+		chain: []string{"query: dial: refused", "dial: refused", "refused"},
+		root:  base,
+	}, {
+		name:  "joined",
+		err:   joinedErrors{fmt.Errorf("dial: %w", base), errors.New("retries exhausted")},
+		chain: []string{"dial: refused\nretries exhausted", "dial: refused", "refused", "retries exhausted"},
+		root:  base,
+	}, {
+		name:  "joined with shared cause",
+		err:   joinedErrors{base, fmt.Errorf("dial: %w", base)},
+		chain: []string{"refused\ndial: refused", "refused", "dial: refused"},
+		root:  base,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain := errorChain(tc.err)
+
+			msgs := make([]string, 0, len(chain))
+			for _, err := range chain {
+				msgs = append(msgs, err.Error())
+			}
+
+			if strings.Join(msgs, "|") != strings.Join(tc.chain, "|") {
+				t.Errorf("expected chain %q, got %q", tc.chain, msgs)
+			}
+
+			if root := rootCause(tc.err); root != tc.root {
+				t.Errorf("expected root cause %v, got %v", tc.root, root)
+			}
+		})
+	}
+}
+
+func TestGroupingHash(t *testing.T) {
+	callSite := []runtime.Frame{{
+		File: "/src/app/repo/repo.go",
+		Line: 42,
+	}}
+
+	// same place of creation, different messages
+	first := groupingHash(rootCause(pkgerrors.Wrap(newRefused(1), "dial")), callSite)
+	second := groupingHash(rootCause(pkgerrors.Wrap(newRefused(2), "dial")), callSite)
+
+	if first != second || strings.Contains(first, "refused") {
+		t.Errorf("expected hash of the place of creation, got %q and %q", first, second)
+	}
+
+	if !strings.Contains(first, "errorstack_test.go:") {
+		t.Errorf("expected hash with the root cause frame, got %q", first)
+	}
+
+	// no stack, grouped by the call site
+	first = groupingHash(fmt.Errorf("user %d not found", 1), callSite)
+	second = groupingHash(fmt.Errorf("user %d not found", 2), callSite)
+
+	if first != second || first != "*errors.errorString@app/repo/repo.go:42" {
+		t.Errorf("expected hash of the type and the call site, got %q and %q", first, second)
+	}
+
+	if hash := groupingHash(errors.New("no call site"), nil); hash != "*errors.errorString" {
+		t.Errorf("expected hash of the type, got %q", hash)
+	}
+}
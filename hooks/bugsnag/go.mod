@@ -6,7 +6,10 @@ require (
 	github.com/bugsnag/bugsnag-go v1.5.3
 	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.9.0
-	github.com/xlab/suplog v1.4.1
+	github.com/xlab/suplog v1.4.2-0.20261017051833-a3d4ac419e46
 )
 
-replace github.com/bugsnag/bugsnag-go => ./bugsnag-go
+replace (
+	github.com/bugsnag/bugsnag-go => ./bugsnag-go
+	github.com/xlab/suplog => ../../
+)
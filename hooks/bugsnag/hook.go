@@ -1,9 +1,12 @@
 package bugsnag
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"runtime"
 	"strings"
+	"sync"
 
 	bugsnag "github.com/bugsnag/bugsnag-go"
 	"github.com/sirupsen/logrus"
@@ -118,6 +121,13 @@ type hook struct {
 	logger   RootLogger
 	stack    stackcache.StackCache
 	notifier *bugsnag.Notifier
+
+	// pending deliveries, waited for on Flush. A WaitGroup doesn't fit, as
+	// deliveries may start while Flush is waiting.
+	mux     sync.Mutex
+	pending int
+	// drained is closed once pending deliveries are done
+	drained chan struct{}
 }
 
 func (h *hook) Levels() []logrus.Level {
@@ -128,10 +138,22 @@ func (h *hook) Fire(e *logrus.Entry) error {
 	var (
 		err        ErrorWithStackFrames
 		errContext bugsnag.Context
+		causes     bugsnag.Causes
+		grouping   bugsnag.GroupingHash
 	)
 
 	// check if we have error in fields
 	if withErr, ok := e.Data["error"].(error); ok {
+		// report the whole chain of causes, grouped by the root cause
+		if chain := errorChain(withErr); len(chain) > 1 {
+			causes = make(bugsnag.Causes, 0, len(chain)-1)
+			for _, cause := range chain[1:] {
+				causes = append(causes, newCause(cause))
+			}
+
+			grouping.Hash = groupingHash(rootCause(withErr), h.stackFrames(e))
+		}
+
 		// check if that error has stack (was wrapped at some point)
 		if withStack, ok := withErr.(ErrorWithStackFrames); ok {
 			// use this error to report, with its original stack
@@ -145,20 +167,20 @@ func (h *hook) Fire(e *logrus.Entry) error {
 			err, parsingErr = newErrorWithPkgErrorsStackTrace(withErr, stackTrace)
 			if parsingErr != nil {
 				// no stack with error (parsing failure), wrap it
-				stackFrames := h.stack.GetStackFrames()
+				stackFrames := h.stackFrames(e)
 				err = newErrorWithStackFrames(withErr, stackFrames)
 			}
 
 			errContext.String = e.Message
 		} else {
 			// no stack with error, wrap it
-			stackFrames := h.stack.GetStackFrames()
+			stackFrames := h.stackFrames(e)
 			err = newErrorWithStackFrames(withErr, stackFrames)
 			errContext.String = e.Message
 		}
 	} else {
 		// no error within fields, construct new one from log message
-		stackFrames := h.stack.GetStackFrames()
+		stackFrames := h.stackFrames(e)
 		err = newErrorWithStackFrames(fmt.Errorf("%s", e.Message), stackFrames)
 	}
 
@@ -178,18 +200,82 @@ func (h *hook) Fire(e *logrus.Entry) error {
 	}
 
 	userData := captureUserMeta(e.Data)
+	// entries are sampled by the logger, the sampled count is kept in fields
 	metaData := fieldsToMetaData(e.Data)
 
+	rawData := []interface{}{severity, metaData, userData}
 	if len(errContext.String) > 0 {
-		_ = h.notifier.NotifySync(err, needSync, severity, metaData, userData, errContext)
+		rawData = append(rawData, errContext)
+	}
+
+	if len(causes) > 0 {
+		rawData = append(rawData, causes, grouping)
+	}
+
+	if needSync {
+		_ = h.notifier.NotifySync(err, true, rawData...)
 		return nil
 	}
 
-	_ = h.notifier.NotifySync(err, needSync, severity, metaData, userData)
+	// deliver in background, but keep track of it for Flush
+	h.startDelivery()
+	go func() {
+		defer h.endDelivery()
+		_ = h.notifier.NotifySync(err, true, rawData...)
+	}()
 
 	return nil
 }
 
+// Flush waits for pending deliveries to Bugsnag, implements suplog.Flusher.
+func (h *hook) Flush(ctx context.Context) error {
+	h.mux.Lock()
+	if h.pending == 0 {
+		h.mux.Unlock()
+		return nil
+	}
+
+	drained := h.drained
+	h.mux.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("bugsnag deliveries are pending: %w", ctx.Err())
+	}
+}
+
+func (h *hook) startDelivery() {
+	h.mux.Lock()
+	if h.pending == 0 {
+		h.drained = make(chan struct{})
+	}
+
+	h.pending++
+	h.mux.Unlock()
+}
+
+func (h *hook) endDelivery() {
+	h.mux.Lock()
+	h.pending--
+	if h.pending == 0 {
+		close(h.drained)
+	}
+
+	h.mux.Unlock()
+}
+
+// stackFrames returns the call stack carried by the entry context,
+// discovering it otherwise.
+func (h *hook) stackFrames(e *logrus.Entry) []runtime.Frame {
+	if frames, ok := stackcache.FramesFromContext(e.Context); ok {
+		return frames
+	}
+
+	return h.stack.GetStackFrames()
+}
+
 func captureUserMeta(fields logrus.Fields) (user bugsnag.User) {
 	if userID, ok := fields["@user.id"].(string); ok {
 		user.Id = userID
@@ -1,11 +1,18 @@
 package bugsnag
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	pkgerrors "github.com/pkg/errors"
+
 	bugsnagHook "github.com/xlab/suplog/hooks/bugsnag"
 
 	"github.com/xlab/suplog"
@@ -33,3 +40,96 @@ func TestBugsnagHook(t *testing.T) {
 	time.Sleep(time.Second)
 	out.Debug("test done")
 }
+
+func TestBugsnagHookFlush(t *testing.T) {
+	hook := bugsnagHook.NewHook(suplog.DefaultLogger, &bugsnagHook.HookOptions{
+		Env: "local",
+	})
+
+	flusher, ok := hook.(suplog.Flusher)
+	if !ok {
+		t.Fatal("expected bugsnag hook to implement suplog.Flusher")
+	}
+
+	out := suplog.NewLogger(os.Stderr, new(suplog.TextFormatter), hook)
+	out.Error("pending delivery")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := flusher.Flush(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBugsnagHookFlushConcurrent(t *testing.T) {
+	hook := bugsnagHook.NewHook(suplog.DefaultLogger, &bugsnagHook.HookOptions{
+		Env: "local",
+	})
+
+	out := suplog.NewLogger(io.Discard, new(suplog.TextFormatter), hook)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				out.Error("delivered while flushing")
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 0; i < 50; i++ {
+		if err := hook.(suplog.Flusher).Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wg.Wait()
+
+	if err := hook.(suplog.Flusher).Flush(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+// joinedErrors mimics errors.Join, which requires Go 1.20.
+type joinedErrors []error
+
+func (e joinedErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+func (e joinedErrors) Unwrap() []error {
+	return e
+}
+
+func TestBugsnagHookErrorChain(t *testing.T) {
+	hook := bugsnagHook.NewHook(suplog.DefaultLogger, &bugsnagHook.HookOptions{
+		Env: "test",
+	})
+	out := suplog.NewLogger(os.Stderr, new(suplog.TextFormatter), hook)
+
+	rootCause := pkgerrors.New("connection refused")
+	joined := joinedErrors{
+		fmt.Errorf("query failed: %w", pkgerrors.Wrap(rootCause, "dial")),
+		errors.New("retries exhausted"),
+	}
+
+	out.WithError(joined).Error("4) with a chain of causes")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := hook.(suplog.Flusher).Flush(ctx); err != nil {
+		t.Error(err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -80,7 +81,12 @@ func (h *hook) Levels() []logrus.Level {
 }
 
 func (h *hook) Fire(e *logrus.Entry) error {
-	caller := h.stack.GetCaller()
+	var caller runtime.Frame
+	if frames, ok := stackcache.FramesFromContext(e.Context); ok {
+		caller = frames[0]
+	} else {
+		caller = h.stack.GetCaller()
+	}
 
 	if len(caller.Function) > 0 {
 		parts := strings.Split(caller.Function, "/")
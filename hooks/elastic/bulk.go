@@ -0,0 +1,203 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/xlab/suplog/batcher"
+)
+
+// add adds the queued document to the batch, reporting whether the batch is full.
+func (h *hook) add(item interface{}) bool {
+	doc := item.(document)
+
+	h.docs = append(h.docs, doc)
+	h.size += len(doc.body)
+
+	return h.size >= h.opt.FlushBytes
+}
+
+// export indexes the batch, if not empty, releasing its size from the buffer.
+func (h *hook) export() {
+	if len(h.docs) > 0 {
+		h.bulk(h.docs)
+		atomic.AddInt64(&h.buffered, -int64(h.size))
+		h.docs, h.size = nil, 0
+	}
+}
+
+// bulk indexes documents, retrying with backoff on network errors, 429 and 5xx
+// responses, and items rejected with such statuses. Other failed items are dropped.
+func (h *hook) bulk(docs []document) {
+	backoff := &batcher.Backoff{
+		Min: h.opt.MinBackoff,
+		Max: h.opt.MaxBackoff,
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, err := h.send(docs)
+
+		switch {
+		case err != nil && !isRetryable(err):
+			h.logger.Errorf("failed to index %d documents into Elasticsearch: %v", len(docs), err)
+			return
+		case err == nil && len(retry) == 0:
+			return
+		case err == nil:
+			docs = retry
+			err = fmt.Errorf("%d items rejected with retryable statuses", len(retry))
+		}
+
+		if attempt == h.opt.MaxRetries {
+			h.logger.Errorf("failed to index %d documents into Elasticsearch: %v", len(docs), err)
+			return
+		}
+
+		if !h.batcher.Wait(backoff.Next()) {
+			return
+		}
+	}
+}
+
+// requestError is returned for bulk requests rejected as a whole.
+type requestError struct {
+	status int
+	msg    string
+}
+
+func (e *requestError) Error() string {
+	return fmt.Sprintf("server returned %d %s: %s", e.status, http.StatusText(e.status), e.msg)
+}
+
+// isRetryable reports whether the failed request should be retried.
+func isRetryable(err error) bool {
+	reqErr, ok := err.(*requestError)
+	if !ok {
+		// network errors
+		return true
+	}
+
+	return isRetryableStatus(reqErr.status)
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status/100 == 5
+}
+
+type bulkResponse struct {
+	Errors bool                                `json:"errors"`
+	Items  []map[string]bulkResponseItemResult `json:"items"`
+}
+
+type bulkResponseItemResult struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// send performs the bulk request, returning documents to retry.
+func (h *hook) send(docs []document) ([]document, error) {
+	body := new(bytes.Buffer)
+	encoder := json.NewEncoder(body)
+
+	for _, doc := range docs {
+		action := map[string]map[string]string{
+			"create": {"_index": doc.index},
+		}
+
+		if err := encoder.Encode(action); err != nil {
+			return nil, err
+		}
+
+		body.Write(bytes.TrimSuffix(doc.body, []byte("\n")))
+		body.WriteByte('\n')
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.opt.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.opt.URL+"/_bulk", body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	if len(h.opt.APIKey) > 0 {
+		req.Header.Set("Authorization", "ApiKey "+h.opt.APIKey)
+	} else if len(h.opt.Username) > 0 {
+		req.SetBasicAuth(h.opt.Username, h.opt.Password)
+	}
+
+	resp, err := h.opt.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &requestError{
+			status: resp.StatusCode,
+			msg:    string(bytes.TrimSpace(msg)),
+		}
+	}
+
+	var result bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, &requestError{
+			status: resp.StatusCode,
+			msg:    fmt.Sprintf("failed to decode bulk response: %v", err),
+		}
+	}
+
+	if !result.Errors {
+		return nil, nil
+	}
+
+	var (
+		retry    []document
+		rejected int
+		reason   string
+	)
+
+	for i, item := range result.Items {
+		if i >= len(docs) {
+			break
+		}
+
+		for _, res := range item {
+			if res.Status/100 == 2 {
+				continue
+			}
+
+			if isRetryableStatus(res.Status) {
+				retry = append(retry, docs[i])
+				continue
+			}
+
+			rejected++
+			if res.Error != nil && len(reason) == 0 {
+				reason = res.Error.Type + ": " + res.Error.Reason
+			}
+		}
+	}
+
+	if rejected > 0 {
+		h.logger.Errorf("failed to index %d documents into Elasticsearch: %s", rejected, reason)
+	}
+
+	return retry, nil
+}
+
+// reportDropped reports entries dropped due to the full buffer.
+func (h *hook) reportDropped(n uint64) {
+	h.logger.Errorf("failed to index %d entries into Elasticsearch, buffer is full", n)
+}
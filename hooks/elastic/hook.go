@@ -0,0 +1,323 @@
+package elastic
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/xlab/suplog/batcher"
+)
+
+const (
+	defaultIndex          = "logs-%{2006.01.02}"
+	defaultFlushBytes     = 5 << 20
+	defaultFlushInterval  = time.Second
+	defaultMaxBufferBytes = 50 << 20
+	defaultMinBackoff     = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	defaultMaxRetries     = 10
+	defaultTimeout        = 30 * time.Second
+)
+
+// queueSize limits documents waiting to be batched, along with MaxBufferBytes.
+const queueSize = 10000
+
+// missingValue replaces fields missing in the entry in index names.
+const missingValue = "unknown"
+
+// HookOptions allows to set additional Hook options.
+type HookOptions struct {
+	// Levels enables this hook for all listed levels, all levels by default.
+	Levels []logrus.Level
+	// URL of the Elasticsearch or OpenSearch cluster (LOG_ELASTIC_URL), e.g. http://localhost:9200.
+	URL string
+	// Index is the index name template (LOG_ELASTIC_INDEX), logs-%{2006.01.02} by default.
+	// Fields are referenced as {field}, time layouts as %{layout} formatted with
+	// the entry time in UTC, other text is kept as is, e.g. logs-{env}-%{2006.01.02}.
+	Index string
+	// Username and Password enable basic auth.
+	Username string
+	Password string
+	// APIKey enables API key auth (LOG_ELASTIC_API_KEY), the base64 encoded id:key.
+	APIKey string
+	// Formatter formats documents, logrus.JSONFormatter by default.
+	Formatter logrus.Formatter
+	// FlushBytes triggers a bulk request once documents exceed it, 5MiB by default.
+	FlushBytes int
+	// FlushInterval is the maximum time documents wait for a bulk request, 1s by default.
+	FlushInterval time.Duration
+	// MaxBufferBytes bounds memory used by documents waiting to be indexed,
+	// newer entries are dropped when it is exceeded. 50MiB by default.
+	MaxBufferBytes int64
+	// MinBackoff and MaxBackoff bound delays between retries, 500ms and 30s by default.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRetries limits retries of a bulk request, 10 by default.
+	MaxRetries int
+	// Timeout bounds a bulk request, 30s by default.
+	Timeout time.Duration
+	// HTTPClient is used for bulk requests, a client with Timeout by default.
+	HTTPClient *http.Client
+}
+
+func checkHookOptions(opt *HookOptions) *HookOptions {
+	if opt == nil {
+		opt = &HookOptions{}
+	}
+
+	if len(opt.Levels) == 0 {
+		opt.Levels = logrus.AllLevels
+	}
+
+	if len(opt.URL) == 0 {
+		opt.URL = os.Getenv("LOG_ELASTIC_URL")
+	}
+
+	opt.URL = strings.TrimSuffix(opt.URL, "/")
+
+	if len(opt.Index) == 0 {
+		opt.Index = os.Getenv("LOG_ELASTIC_INDEX")
+		if len(opt.Index) == 0 {
+			opt.Index = defaultIndex
+		}
+	}
+
+	if len(opt.APIKey) == 0 {
+		opt.APIKey = os.Getenv("LOG_ELASTIC_API_KEY")
+	}
+
+	if opt.Formatter == nil {
+		opt.Formatter = &logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+		}
+	}
+
+	if opt.FlushBytes <= 0 {
+		opt.FlushBytes = defaultFlushBytes
+	}
+
+	if opt.FlushInterval <= 0 {
+		opt.FlushInterval = defaultFlushInterval
+	}
+
+	if opt.MaxBufferBytes <= 0 {
+		opt.MaxBufferBytes = defaultMaxBufferBytes
+	}
+
+	if opt.MinBackoff <= 0 {
+		opt.MinBackoff = defaultMinBackoff
+	}
+
+	if opt.MaxBackoff <= 0 {
+		opt.MaxBackoff = defaultMaxBackoff
+	}
+
+	if opt.MaxRetries <= 0 {
+		opt.MaxRetries = defaultMaxRetries
+	}
+
+	if opt.Timeout <= 0 {
+		opt.Timeout = defaultTimeout
+	}
+
+	if opt.HTTPClient == nil {
+		opt.HTTPClient = &http.Client{
+			Timeout: opt.Timeout,
+		}
+	}
+
+	return opt
+}
+
+type RootLogger interface {
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Printf(format string, args ...interface{})
+}
+
+// NewHook initializes a new logrus.Hook that indexes entries into Elasticsearch
+// or OpenSearch using the _bulk API. Documents are buffered and sent in background,
+// failed requests and items are retried with backoff. The hook implements
+// Flush and Close, called on suplog Close.
+func NewHook(logger RootLogger, opt *HookOptions) logrus.Hook {
+	opt = checkHookOptions(opt)
+
+	h := &hook{
+		opt:    opt,
+		logger: logger,
+		index:  parseIndexTemplate(opt.Index),
+	}
+
+	h.batcher = batcher.New(batcher.Options{
+		QueueSize:     queueSize,
+		FlushInterval: opt.FlushInterval,
+		Add:           h.add,
+		Export:        h.export,
+		Dropped:       h.reportDropped,
+	})
+
+	return h
+}
+
+type hook struct {
+	opt    *HookOptions
+	logger RootLogger
+	index  []indexPart
+
+	batcher *batcher.Batcher
+	// buffered is the size of documents waiting to be indexed
+	buffered int64
+
+	// docs and size of the batch of the background goroutine
+	docs []document
+	size int
+}
+
+type document struct {
+	index string
+	body  []byte
+}
+
+func (h *hook) Levels() []logrus.Level {
+	return h.opt.Levels
+}
+
+func (h *hook) Fire(e *logrus.Entry) error {
+	if h.batcher.Closed() {
+		return nil
+	}
+
+	serialized, err := h.opt.Formatter.Format(e)
+	if err != nil {
+		return fmt.Errorf("failed to format entry for Elasticsearch: %w", err)
+	}
+
+	doc := document{
+		index: h.indexName(e),
+		body:  append([]byte(nil), serialized...),
+	}
+
+	size := int64(len(doc.body))
+	if atomic.AddInt64(&h.buffered, size) > h.opt.MaxBufferBytes {
+		atomic.AddInt64(&h.buffered, -size)
+		h.batcher.Drop()
+
+		return nil
+	}
+
+	if !h.batcher.Enqueue(doc) {
+		atomic.AddInt64(&h.buffered, -size)
+	}
+
+	return nil
+}
+
+// Flush blocks until documents queued before the call are indexed, or ctx is done.
+func (h *hook) Flush(ctx context.Context) error {
+	return h.batcher.Flush(ctx)
+}
+
+// Close indexes queued documents and stops the hook.
+func (h *hook) Close(ctx context.Context) error {
+	return h.batcher.Close(ctx)
+}
+
+// indexPart is either a literal text, a field reference, or a time layout.
+type indexPart struct {
+	text   string
+	field  string
+	layout string
+}
+
+// parseIndexTemplate splits the template into literal text, {field} references
+// and %{layout} time layouts. Unterminated references are kept as text.
+func parseIndexTemplate(template string) []indexPart {
+	var parts []indexPart
+
+	for len(template) > 0 {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			parts = append(parts, indexPart{text: template})
+			break
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			parts = append(parts, indexPart{text: template})
+			break
+		}
+
+		end += start
+		ref := template[start+1 : end]
+
+		text := template[:start]
+		if strings.HasSuffix(text, "%") {
+			text = text[:len(text)-1]
+		}
+
+		if len(text) > 0 {
+			parts = append(parts, indexPart{text: text})
+		}
+
+		if start > 0 && template[start-1] == '%' {
+			parts = append(parts, indexPart{layout: ref})
+		} else {
+			parts = append(parts, indexPart{field: ref})
+		}
+
+		template = template[end+1:]
+	}
+
+	return parts
+}
+
+// indexName renders the index name template for the entry.
+func (h *hook) indexName(e *logrus.Entry) string {
+	var b strings.Builder
+
+	for _, part := range h.index {
+		switch {
+		case len(part.text) > 0:
+			b.WriteString(part.text)
+			continue
+		case len(part.layout) > 0:
+			b.WriteString(e.Time.UTC().Format(part.layout))
+			continue
+		}
+
+		value := missingValue
+		if v, ok := e.Data[part.field]; ok {
+			value = fmt.Sprint(v)
+		}
+
+		b.WriteString(indexValue(value))
+	}
+
+	return b.String()
+}
+
+// indexValue returns the value usable in index names: lower-cased,
+// with characters not allowed in index names replaced with '_'.
+func indexValue(v string) string {
+	v = strings.Map(func(r rune) rune {
+		switch r {
+		case '\\', '/', '*', '?', '"', '<', '>', '|', ' ', ',', '#', ':':
+			return '_'
+		}
+
+		return r
+	}, strings.ToLower(v))
+
+	if len(v) == 0 {
+		return missingValue
+	}
+
+	return v
+}
@@ -0,0 +1,260 @@
+package elastic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	elasticHook "github.com/xlab/suplog/hooks/elastic"
+	"github.com/xlab/suplog/suplogtest"
+
+	"github.com/xlab/suplog"
+)
+
+type indexedDoc struct {
+	index  string
+	fields map[string]interface{}
+}
+
+// server is an httptest stand-in of the _bulk API, the respond func
+// returns the status of each item, 201 by default.
+type server struct {
+	*httptest.Server
+
+	mux     sync.Mutex
+	docs    []indexedDoc
+	header  http.Header
+	respond func(doc indexedDoc) int
+}
+
+func newServer(t *testing.T) *server {
+	s := &server{}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.mux.Lock()
+		defer s.mux.Unlock()
+
+		s.header = r.Header
+
+		var (
+			items   []string
+			errored bool
+		)
+
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			scanner.Scan()
+
+			doc := indexedDoc{
+				index: action["create"]["_index"],
+			}
+
+			if err := json.Unmarshal(scanner.Bytes(), &doc.fields); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			status := http.StatusCreated
+			if s.respond != nil {
+				status = s.respond(doc)
+			}
+
+			if status == http.StatusCreated {
+				s.docs = append(s.docs, doc)
+				items = append(items, `{"create":{"status":201}}`)
+			} else {
+				errored = true
+				items = append(items, fmt.Sprintf(`{"create":{"status":%d,"error":{"type":"test_exception","reason":"rejected"}}}`, status))
+			}
+		}
+
+		fmt.Fprintf(w, `{"errors":%v,"items":[%s]}`, errored, strings.Join(items, ","))
+	}))
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *server) indexed() []indexedDoc {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return append([]indexedDoc(nil), s.docs...)
+}
+
+func TestElasticHook(t *testing.T) {
+	srv := newServer(t)
+
+	hook := elasticHook.NewHook(suplog.DefaultLogger, &elasticHook.HookOptions{
+		URL:    srv.URL,
+		Index:  "logs-{env}-%{2006.01.02}",
+		APIKey: "a2V5",
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	ts := time.Date(2021, 6, 1, 23, 0, 0, 0, time.FixedZone("UTC+3", 3*3600))
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.WithField("env", "Prod").WithField("user", "max").WithTime(ts).Error("query failed")
+	logger.WithTime(ts).Info("no env")
+
+	suplogtest.FlushHook(t, hook)
+
+	docs := srv.indexed()
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(docs))
+	}
+
+	if docs[0].index != "logs-prod-2021.06.01" || docs[1].index != "logs-unknown-2021.06.01" {
+		t.Errorf("unexpected indices: %s, %s", docs[0].index, docs[1].index)
+	}
+
+	// same layout as suplog.JSONFormatter
+	out := new(bytes.Buffer)
+	suplog.NewLogger(out, new(suplog.JSONFormatter)).
+		WithField("env", "Prod").WithField("user", "max").WithTime(ts).Error("query failed")
+
+	var exp map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &exp); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, v := range exp {
+		if k == "time" {
+			continue
+		}
+
+		if docs[0].fields[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, docs[0].fields[k])
+		}
+	}
+
+	if len(docs[0].fields) != len(exp) {
+		t.Errorf("unexpected document: %v", docs[0].fields)
+	}
+
+	if srv.header.Get("Authorization") != "ApiKey a2V5" {
+		t.Errorf("expected API key auth, got %v", srv.header)
+	}
+}
+
+func TestElasticHookIndexTemplate(t *testing.T) {
+	srv := newServer(t)
+
+	hook := elasticHook.NewHook(suplog.DefaultLogger, &elasticHook.HookOptions{
+		URL:   srv.URL,
+		Index: "v1-shipments-{env}-%{2006.01}",
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	ts := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.WithField("env", "prod").WithTime(ts).Info("shipped")
+
+	suplogtest.FlushHook(t, hook)
+
+	if docs := srv.indexed(); len(docs) != 1 || docs[0].index != "v1-shipments-prod-2021.06" {
+		t.Errorf("expected literal text kept in the index name, got %v", docs)
+	}
+}
+
+func TestElasticHookItemFailures(t *testing.T) {
+	srv := newServer(t)
+
+	attempts := make(map[string]int)
+	srv.respond = func(doc indexedDoc) int {
+		msg := doc.fields["msg"].(string)
+		attempts[msg]++
+
+		switch {
+		case msg == "throttled" && attempts[msg] == 1:
+			return http.StatusTooManyRequests
+		case msg == "invalid":
+			return http.StatusBadRequest
+		default:
+			return http.StatusCreated
+		}
+	}
+
+	rec := suplogtest.NewRecorder(nil)
+
+	hook := elasticHook.NewHook(rec, &elasticHook.HookOptions{
+		URL:        srv.URL,
+		MinBackoff: 10 * time.Millisecond,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("ok")
+	logger.Info("throttled")
+	logger.Info("invalid")
+
+	suplogtest.FlushHook(t, hook)
+
+	var msgs []string
+	for _, doc := range srv.indexed() {
+		msgs = append(msgs, doc.fields["msg"].(string))
+	}
+
+	if strings.Join(msgs, ",") != "ok,throttled" {
+		t.Errorf("expected throttled item retried and invalid dropped, got %v", msgs)
+	}
+
+	if attempts["invalid"] != 1 || attempts["throttled"] != 2 {
+		t.Errorf("unexpected attempts: %v", attempts)
+	}
+
+	rec.AssertLogged(t, suplog.ErrorLevel, "failed to index 1 documents into Elasticsearch", nil)
+}
+
+func TestElasticHookBufferLimit(t *testing.T) {
+	srv := newServer(t)
+
+	hook := elasticHook.NewHook(suplog.DefaultLogger, &elasticHook.HookOptions{
+		URL:            srv.URL,
+		Formatter:      new(suplog.JSONFormatter),
+		FlushInterval:  time.Hour,
+		MaxBufferBytes: 200,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	for i := 0; i < 5; i++ {
+		logger.Infof("entry %d", i)
+	}
+
+	suplogtest.FlushHook(t, hook)
+
+	if docs := srv.indexed(); len(docs) == 0 || len(docs) >= 5 {
+		t.Errorf("expected entries over the buffer limit dropped, got %d documents", len(docs))
+	}
+
+	logger.Info("after flush")
+	suplogtest.FlushHook(t, hook)
+
+	if docs := srv.indexed(); docs[len(docs)-1].fields["msg"] != "after flush" {
+		t.Errorf("expected the buffer released after flush, got %v", docs[len(docs)-1].fields)
+	}
+}
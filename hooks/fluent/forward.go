@@ -0,0 +1,211 @@
+package fluent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/xlab/suplog/batcher"
+)
+
+const minBackoff = 100 * time.Millisecond
+
+// chunk groups encoded entries of a tag.
+type chunk struct {
+	tag     string
+	entries bytes.Buffer
+	size    int
+}
+
+// conn is a connection to the forward server.
+type conn struct {
+	net.Conn
+	dec *msgpack.Decoder
+}
+
+// add adds the queued entry to the chunk of its tag, reporting whether chunks are full.
+func (h *hook) add(item interface{}) bool {
+	msg := item.(message)
+
+	ch, ok := h.index[msg.tag]
+	if !ok {
+		ch = &chunk{
+			tag: msg.tag,
+		}
+
+		h.index[msg.tag] = ch
+		h.chunks = append(h.chunks, ch)
+	}
+
+	ch.entries.Write(msg.entry)
+	ch.size++
+	h.size += len(msg.entry)
+
+	return h.size >= h.opt.BatchSize
+}
+
+// export sends chunks over the connection, connecting if needed.
+func (h *hook) export() {
+	for _, ch := range h.chunks {
+		h.conn = h.send(h.conn, ch)
+	}
+
+	h.chunks = nil
+	h.index = make(map[string]*chunk)
+	h.size = 0
+}
+
+// disconnect closes the connection, if any.
+func (h *hook) disconnect() {
+	if h.conn != nil {
+		h.conn.Close()
+		h.conn = nil
+	}
+}
+
+// send writes the chunk, reconnecting and resending it on failures.
+// Returns the connection to use for the following chunks.
+func (h *hook) send(c *conn, ch *chunk) *conn {
+	data, id, err := h.encodeChunk(ch)
+	if err != nil {
+		h.logger.Errorf("failed to encode Fluentd chunk: %v", err)
+		return c
+	}
+
+	for attempt := 1; ; attempt++ {
+		if c == nil {
+			if c = h.connect(); c == nil {
+				h.logger.Errorf("failed to send %d entries to Fluentd, hook is closed", ch.size)
+				return nil
+			}
+		}
+
+		err := h.write(c, data, id)
+		if err == nil {
+			return c
+		}
+
+		c.Close()
+		c = nil
+
+		if attempt == h.opt.MaxRetries {
+			h.logger.Errorf("failed to send %d entries to Fluentd: %v", ch.size, err)
+			return nil
+		}
+	}
+}
+
+var errAckMismatch = errors.New("unexpected chunk acknowledgement")
+
+// write sends the message, waiting for the acknowledgement of the chunk if required.
+func (h *hook) write(c *conn, data []byte, id string) error {
+	if err := c.SetWriteDeadline(time.Now().Add(h.opt.WriteTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := c.Write(data); err != nil {
+		return err
+	}
+
+	if !h.opt.RequireAck {
+		return nil
+	}
+
+	if err := c.SetReadDeadline(time.Now().Add(h.opt.AckTimeout)); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Ack string `msgpack:"ack"`
+	}
+
+	if err := c.dec.Decode(&resp); err != nil {
+		return err
+	}
+
+	if resp.Ack != id {
+		return errAckMismatch
+	}
+
+	return nil
+}
+
+// encodeChunk encodes [tag, entries, option] message of PackedForward mode.
+func (h *hook) encodeChunk(ch *chunk) ([]byte, string, error) {
+	option := map[string]interface{}{
+		"size": ch.size,
+	}
+
+	var id string
+
+	if h.opt.RequireAck {
+		var raw [16]byte
+		if _, err := rand.Read(raw[:]); err != nil {
+			return nil, "", err
+		}
+
+		id = base64.StdEncoding.EncodeToString(raw[:])
+		option["chunk"] = id
+	}
+
+	b := new(bytes.Buffer)
+	enc := msgpack.NewEncoder(b)
+
+	if err := enc.EncodeArrayLen(3); err != nil {
+		return nil, "", err
+	}
+
+	if err := enc.EncodeString(ch.tag); err != nil {
+		return nil, "", err
+	}
+
+	if err := enc.EncodeBytes(ch.entries.Bytes()); err != nil {
+		return nil, "", err
+	}
+
+	if err := enc.EncodeMap(option); err != nil {
+		return nil, "", err
+	}
+
+	return b.Bytes(), id, nil
+}
+
+// connect dials until connected, doubling the delay between attempts.
+// Returns nil if the hook is closed meanwhile.
+func (h *hook) connect() *conn {
+	backoff := &batcher.Backoff{
+		Min: minBackoff,
+		Max: h.opt.MaxBackoff,
+	}
+
+	reported := false
+
+	for {
+		c, err := net.DialTimeout(h.opt.Network, h.opt.Addr, h.opt.DialTimeout)
+		if err == nil {
+			return &conn{
+				Conn: c,
+				dec:  msgpack.NewDecoder(c),
+			}
+		}
+
+		if !reported {
+			h.logger.Errorf("failed to connect to Fluentd: %v", err)
+			reported = true
+		}
+
+		if !h.batcher.Wait(backoff.Next()) {
+			return nil
+		}
+	}
+}
+
+// reportDropped reports entries dropped due to the full buffer.
+func (h *hook) reportDropped(n uint64) {
+	h.logger.Errorf("failed to send %d entries to Fluentd, buffer is full", n)
+}
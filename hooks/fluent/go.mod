@@ -0,0 +1,11 @@
+module github.com/xlab/suplog/hooks/fluent
+
+go 1.16
+
+require (
+	github.com/sirupsen/logrus v1.9.0
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	github.com/xlab/suplog v1.4.2-0.20261017051833-a3d4ac419e46
+)
+
+replace github.com/xlab/suplog => ../../
@@ -0,0 +1,284 @@
+package fluent
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/xlab/suplog/batcher"
+)
+
+// Field names of the record besides entry fields.
+const (
+	MessageField = "message"
+	LevelField   = "level"
+)
+
+// loggerField is the field that carries the name of suplog named loggers.
+const loggerField = "logger"
+
+const (
+	defaultAddr          = "127.0.0.1:24224"
+	defaultTag           = "app"
+	defaultTagField      = "tag"
+	defaultBufferSize    = 10000
+	defaultBatchSize     = 1 << 20
+	defaultFlushInterval = time.Second
+	defaultDialTimeout   = 5 * time.Second
+	defaultWriteTimeout  = 5 * time.Second
+	defaultAckTimeout    = 10 * time.Second
+	defaultMaxBackoff    = 5 * time.Second
+	defaultMaxRetries    = 10
+)
+
+// HookOptions allows to set additional Hook options.
+type HookOptions struct {
+	// Levels enables this hook for all listed levels, all levels by default.
+	Levels []logrus.Level
+	// Network is either "tcp" or "unix" (LOG_FLUENT_NETWORK), tcp by default.
+	Network string
+	// Addr of the Fluentd or Fluent Bit forward input (LOG_FLUENT_ADDR), 127.0.0.1:24224 by default.
+	Addr string
+	// Tag of entries (LOG_FLUENT_TAG), app by default. Entries of named loggers
+	// are tagged with the logger name appended, e.g. app.db.
+	Tag string
+	// TagField is the field that overrides the tag of the entry, tag by default.
+	TagField string
+	// RequireAck enables at-least-once delivery, each chunk is resent
+	// until the server acknowledges it.
+	RequireAck bool
+	// BufferSize limits entries buffered while the server is unavailable,
+	// newer entries are dropped when the buffer is full. 10000 by default.
+	BufferSize int
+	// BatchSize triggers sending of a chunk once encoded entries exceed it, 1MiB by default.
+	BatchSize int
+	// FlushInterval is the maximum time entries wait for a chunk, 1s by default.
+	FlushInterval time.Duration
+	// DialTimeout bounds connecting to the server, 5s by default.
+	DialTimeout time.Duration
+	// WriteTimeout bounds writing a chunk, 5s by default.
+	WriteTimeout time.Duration
+	// AckTimeout bounds waiting for the chunk acknowledgement, 10s by default.
+	AckTimeout time.Duration
+	// MaxBackoff is the maximum delay between reconnection attempts,
+	// the delay is doubled after each failed attempt. 5s by default.
+	MaxBackoff time.Duration
+	// MaxRetries limits attempts to send a chunk, 10 by default.
+	MaxRetries int
+}
+
+func checkHookOptions(opt *HookOptions) *HookOptions {
+	if opt == nil {
+		opt = &HookOptions{}
+	}
+
+	if len(opt.Levels) == 0 {
+		opt.Levels = logrus.AllLevels
+	}
+
+	if len(opt.Network) == 0 {
+		opt.Network = os.Getenv("LOG_FLUENT_NETWORK")
+		if len(opt.Network) == 0 {
+			opt.Network = "tcp"
+		}
+	}
+
+	if len(opt.Addr) == 0 {
+		opt.Addr = os.Getenv("LOG_FLUENT_ADDR")
+		if len(opt.Addr) == 0 {
+			opt.Addr = defaultAddr
+		}
+	}
+
+	if len(opt.Tag) == 0 {
+		opt.Tag = os.Getenv("LOG_FLUENT_TAG")
+		if len(opt.Tag) == 0 {
+			opt.Tag = defaultTag
+		}
+	}
+
+	if len(opt.TagField) == 0 {
+		opt.TagField = defaultTagField
+	}
+
+	if opt.BufferSize <= 0 {
+		opt.BufferSize = defaultBufferSize
+	}
+
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = defaultBatchSize
+	}
+
+	if opt.FlushInterval <= 0 {
+		opt.FlushInterval = defaultFlushInterval
+	}
+
+	if opt.DialTimeout <= 0 {
+		opt.DialTimeout = defaultDialTimeout
+	}
+
+	if opt.WriteTimeout <= 0 {
+		opt.WriteTimeout = defaultWriteTimeout
+	}
+
+	if opt.AckTimeout <= 0 {
+		opt.AckTimeout = defaultAckTimeout
+	}
+
+	if opt.MaxBackoff <= 0 {
+		opt.MaxBackoff = defaultMaxBackoff
+	}
+
+	if opt.MaxRetries <= 0 {
+		opt.MaxRetries = defaultMaxRetries
+	}
+
+	return opt
+}
+
+type RootLogger interface {
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Printf(format string, args ...interface{})
+}
+
+// NewHook initializes a new logrus.Hook that sends entries to Fluentd or Fluent Bit
+// using the Forward protocol in PackedForward mode. Entries are sent in background,
+// buffered while the server is unavailable. The hook implements Flush and Close,
+// called on suplog Close.
+func NewHook(logger RootLogger, opt *HookOptions) logrus.Hook {
+	opt = checkHookOptions(opt)
+
+	h := &hook{
+		opt:    opt,
+		logger: logger,
+		index:  make(map[string]*chunk),
+	}
+
+	h.batcher = batcher.New(batcher.Options{
+		QueueSize:     opt.BufferSize,
+		FlushInterval: opt.FlushInterval,
+		Add:           h.add,
+		Export:        h.export,
+		Dropped:       h.reportDropped,
+		Stop:          h.disconnect,
+	})
+
+	return h
+}
+
+type hook struct {
+	opt    *HookOptions
+	logger RootLogger
+
+	batcher *batcher.Batcher
+
+	// connection and chunks of the background goroutine
+	conn   *conn
+	chunks []*chunk
+	index  map[string]*chunk
+	size   int
+}
+
+// message is an encoded entry along with its tag.
+type message struct {
+	tag   string
+	entry []byte
+}
+
+func (h *hook) Levels() []logrus.Level {
+	return h.opt.Levels
+}
+
+func (h *hook) Fire(e *logrus.Entry) error {
+	if h.batcher.Closed() {
+		return nil
+	}
+
+	tag := h.opt.Tag
+	if name, ok := e.Data[loggerField].(string); ok && len(name) > 0 {
+		tag += "." + name
+	}
+
+	record := make(map[string]interface{}, len(e.Data)+2)
+
+	for k, v := range e.Data {
+		if k == h.opt.TagField {
+			if entryTag, ok := v.(string); ok && len(entryTag) > 0 {
+				tag = entryTag
+				continue
+			}
+		}
+
+		record[k] = recordValue(v)
+	}
+
+	record[MessageField] = e.Message
+	record[LevelField] = e.Level.String()
+
+	entry, err := encodeEntry(e.Time, record)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry for Fluentd: %w", err)
+	}
+
+	h.batcher.Enqueue(message{tag: tag, entry: entry})
+
+	return nil
+}
+
+// Flush blocks until entries queued before the call are sent, or ctx is done.
+func (h *hook) Flush(ctx context.Context) error {
+	return h.batcher.Flush(ctx)
+}
+
+// Close sends queued entries and closes the connection.
+func (h *hook) Close(ctx context.Context) error {
+	return h.batcher.Close(ctx)
+}
+
+func recordValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// eventTimeExt is the msgpack extension type of EventTime.
+const eventTimeExt = 0
+
+// encodeEntry encodes [EventTime, record] entry of PackedForward mode.
+func encodeEntry(ts time.Time, record map[string]interface{}) ([]byte, error) {
+	b := new(bytes.Buffer)
+
+	enc := msgpack.NewEncoder(b)
+	enc.SetCustomStructTag("json")
+
+	if err := enc.EncodeArrayLen(2); err != nil {
+		return nil, err
+	}
+
+	// EventTime is fixext 8 with big-endian seconds and nanoseconds
+	var eventTime [10]byte
+	eventTime[0] = 0xd7
+	eventTime[1] = eventTimeExt
+	binary.BigEndian.PutUint32(eventTime[2:], uint32(ts.Unix()))
+	binary.BigEndian.PutUint32(eventTime[6:], uint32(ts.Nanosecond()))
+	b.Write(eventTime[:])
+
+	if err := enc.EncodeMap(record); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
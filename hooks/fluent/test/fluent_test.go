@@ -0,0 +1,318 @@
+package fluent
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	fluentHook "github.com/xlab/suplog/hooks/fluent"
+	"github.com/xlab/suplog/suplogtest"
+
+	"github.com/xlab/suplog"
+)
+
+type eventTime struct {
+	time.Time
+}
+
+func (t *eventTime) MarshalMsgpack() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+
+	return b, nil
+}
+
+func (t *eventTime) UnmarshalMsgpack(b []byte) error {
+	if len(b) != 8 {
+		return errors.New("invalid EventTime")
+	}
+
+	t.Time = time.Unix(int64(binary.BigEndian.Uint32(b)), int64(binary.BigEndian.Uint32(b[4:])))
+
+	return nil
+}
+
+func init() {
+	msgpack.RegisterExt(0, (*eventTime)(nil))
+}
+
+type received struct {
+	tag    string
+	chunk  string
+	size   int
+	time   time.Time
+	record map[string]interface{}
+}
+
+// server is an in-process fake of the Forward input, dropConns makes it close
+// the first connections after reading a message without acknowledging it.
+type server struct {
+	ln        net.Listener
+	entries   chan received
+	dropConns int32
+}
+
+func newServer(t *testing.T, network, addr string) *server {
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{
+		ln:      ln,
+		entries: make(chan received, 100),
+	}
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go s.serve(c)
+		}
+	}()
+
+	t.Cleanup(func() {
+		ln.Close()
+	})
+
+	return s
+}
+
+func (s *server) serve(c net.Conn) {
+	defer c.Close()
+
+	dec := msgpack.NewDecoder(c)
+	enc := msgpack.NewEncoder(c)
+
+	for {
+		if n, err := dec.DecodeArrayLen(); err != nil || n != 3 {
+			return
+		}
+
+		tag, err := dec.DecodeString()
+		if err != nil {
+			return
+		}
+
+		entries, err := dec.DecodeBytes()
+		if err != nil {
+			return
+		}
+
+		option, err := dec.DecodeMap()
+		if err != nil {
+			return
+		}
+
+		if atomic.AddInt32(&s.dropConns, -1) >= 0 {
+			return
+		}
+
+		chunk, _ := option["chunk"].(string)
+		size, _ := option["size"].(int8)
+
+		entryDec := msgpack.NewDecoder(bytes.NewReader(entries))
+		for {
+			if _, err := entryDec.DecodeArrayLen(); err == io.EOF {
+				break
+			} else if err != nil {
+				return
+			}
+
+			var ts eventTime
+			if err := entryDec.Decode(&ts); err != nil {
+				return
+			}
+
+			record, err := entryDec.DecodeMap()
+			if err != nil {
+				return
+			}
+
+			s.entries <- received{
+				tag:    tag,
+				chunk:  chunk,
+				size:   int(size),
+				time:   ts.Time,
+				record: record,
+			}
+		}
+
+		if len(chunk) > 0 {
+			if err := enc.Encode(map[string]string{"ack": chunk}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *server) next(t *testing.T) received {
+	select {
+	case entry := <-s.entries:
+		return entry
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for an entry")
+		return received{}
+	}
+}
+
+func TestFluentHook(t *testing.T) {
+	srv := newServer(t, "tcp", "127.0.0.1:0")
+
+	hook := fluentHook.NewHook(suplog.DefaultLogger, &fluentHook.HookOptions{
+		Addr: srv.ln.Addr().String(),
+		Tag:  "api",
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	ts := time.Unix(1622545200, 42)
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Named("db").WithTime(ts).WithField("user", "max").WithError(errors.New("timeout")).Error("query failed")
+	logger.WithField("tag", "audit.login").WithField("attempt", 3).Info("login")
+
+	suplogtest.FlushHook(t, hook)
+
+	entry := srv.next(t)
+	if entry.tag != "api.db" || !entry.time.Equal(ts) {
+		t.Errorf("unexpected tag %s or time %v", entry.tag, entry.time)
+	}
+
+	exp := map[string]interface{}{
+		"message": "query failed",
+		"level":   "error",
+		"logger":  "db",
+		"user":    "max",
+		"error":   "timeout",
+	}
+
+	for k, v := range exp {
+		if entry.record[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, entry.record[k])
+		}
+	}
+
+	entry = srv.next(t)
+	if entry.tag != "audit.login" || entry.record["attempt"] != int8(3) {
+		t.Errorf("expected tag from the field, got %s %v", entry.tag, entry.record)
+	}
+
+	if _, ok := entry.record["tag"]; ok {
+		t.Errorf("expected tag field removed, got %v", entry.record)
+	}
+}
+
+func TestFluentHookAck(t *testing.T) {
+	srv := newServer(t, "unix", filepath.Join(t.TempDir(), "fluent.sock"))
+	srv.dropConns = 1
+
+	hook := fluentHook.NewHook(suplog.DefaultLogger, &fluentHook.HookOptions{
+		Network:    "unix",
+		Addr:       srv.ln.Addr().String(),
+		RequireAck: true,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("first")
+	logger.Info("second")
+
+	suplogtest.FlushHook(t, hook)
+
+	for _, msg := range []string{"first", "second"} {
+		entry := srv.next(t)
+		if entry.record["message"] != msg || len(entry.chunk) == 0 || entry.size != 2 {
+			t.Errorf("expected %s resent in an acknowledged chunk of 2, got %+v", msg, entry)
+		}
+	}
+}
+
+func TestFluentHookBuffering(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+	ln.Close()
+
+	rec := suplogtest.NewRecorder(nil)
+
+	hook := fluentHook.NewHook(rec, &fluentHook.HookOptions{
+		Addr:          addr,
+		BufferSize:    2,
+		FlushInterval: 10 * time.Millisecond,
+		MaxBackoff:    50 * time.Millisecond,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("first")
+
+	// wait for the chunk to be taken from the buffer
+	time.Sleep(200 * time.Millisecond)
+
+	for _, msg := range []string{"second", "third", "dropped"} {
+		logger.Info(msg)
+	}
+
+	srv := newServer(t, "tcp", addr)
+
+	suplogtest.FlushHook(t, hook)
+
+	for _, msg := range []string{"first", "second", "third"} {
+		if entry := srv.next(t); entry.record["message"] != msg {
+			t.Errorf("expected %s, got %v", msg, entry.record["message"])
+		}
+	}
+
+	select {
+	case entry := <-srv.entries:
+		t.Errorf("expected entries over the buffer size dropped, got %v", entry.record)
+	default:
+	}
+
+	rec.AssertLogged(t, suplog.ErrorLevel, "failed to connect to Fluentd", nil)
+	rec.AssertLogged(t, suplog.ErrorLevel, "failed to send 1 entries to Fluentd, buffer is full", nil)
+}
+
+func TestFluentHookClosedUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+	ln.Close()
+
+	rec := suplogtest.NewRecorder(nil)
+
+	hook := fluentHook.NewHook(rec, &fluentHook.HookOptions{
+		Addr:          addr,
+		RequireAck:    true,
+		FlushInterval: 10 * time.Millisecond,
+	})
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("unsent")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	hook.(suplog.Closer).Close(ctx)
+
+	rec.AssertLogged(t, suplog.ErrorLevel, "failed to send 1 entries to Fluentd, hook is closed", nil)
+}
@@ -0,0 +1,11 @@
+module github.com/xlab/suplog/hooks/journald
+
+go 1.16
+
+require (
+	github.com/sirupsen/logrus v1.9.0
+	github.com/xlab/suplog v1.4.2-0.20261017051833-a3d4ac419e46
+	golang.org/x/sys v0.30.0
+)
+
+replace github.com/xlab/suplog => ../../
@@ -0,0 +1,269 @@
+package journald
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSocketPath is the path of the journald native protocol socket.
+const DefaultSocketPath = "/run/systemd/journal/socket"
+
+// Field names of the debug hook, mapped onto CODE_FILE, CODE_LINE and CODE_FUNC.
+const (
+	fnField  = "fn"
+	srcField = "src"
+)
+
+// maxFieldNameLen is the limit of journal field names.
+const maxFieldNameLen = 64
+
+// reservedFields are set by the hook, entry fields with such names are prefixed with FIELD_.
+var reservedFields = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+	"CODE_FILE":         true,
+	"CODE_LINE":         true,
+	"CODE_FUNC":         true,
+}
+
+// HookOptions allows to set additional Hook options.
+type HookOptions struct {
+	// Levels enables this hook for all listed levels, all levels by default.
+	Levels []logrus.Level
+	// SocketPath is the path of the journal socket (LOG_JOURNALD_SOCKET), DefaultSocketPath by default.
+	SocketPath string
+	// Identifier sets SYSLOG_IDENTIFIER of entries, the executable name by default.
+	Identifier string
+}
+
+func checkHookOptions(opt *HookOptions) *HookOptions {
+	if opt == nil {
+		opt = &HookOptions{}
+	}
+
+	if len(opt.Levels) == 0 {
+		opt.Levels = logrus.AllLevels
+	}
+
+	if len(opt.SocketPath) == 0 {
+		opt.SocketPath = os.Getenv("LOG_JOURNALD_SOCKET")
+		if len(opt.SocketPath) == 0 {
+			opt.SocketPath = DefaultSocketPath
+		}
+	}
+
+	if len(opt.Identifier) == 0 {
+		opt.Identifier = filepath.Base(os.Args[0])
+	}
+
+	return opt
+}
+
+type RootLogger interface {
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Printf(format string, args ...interface{})
+}
+
+// NewHook initializes a new logrus.Hook that sends entries to systemd-journald
+// using the native protocol. Fields are sent as upper-cased journal fields,
+// large entries are passed as sealed memfd. Supported on Linux only. While journald
+// is unavailable entries are dropped, the failure is reported once.
+func NewHook(logger RootLogger, opt *HookOptions) logrus.Hook {
+	return &hook{
+		opt:    checkHookOptions(opt),
+		logger: logger,
+	}
+}
+
+type hook struct {
+	opt    *HookOptions
+	logger RootLogger
+
+	mux  sync.Mutex
+	conn *net.UnixConn
+	// failing is set once a failure is reported, until an entry is sent again
+	failing bool
+}
+
+func (h *hook) Levels() []logrus.Level {
+	return h.opt.Levels
+}
+
+func (h *hook) Fire(e *logrus.Entry) error {
+	data := h.encode(e)
+
+	h.mux.Lock()
+	err := h.send(data)
+	// report the failure once, dropping entries until journald is available again
+	report := err != nil && !h.failing
+	h.failing = err != nil
+	h.mux.Unlock()
+
+	if report {
+		// the hook may fire again for this entry, so it's logged without holding the lock
+		h.logger.Errorf("failed to send entry to journald, dropping entries until it is available: %v", err)
+	}
+
+	return nil
+}
+
+// Close closes the journal socket.
+func (h *hook) Close(ctx context.Context) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if h.conn == nil {
+		return nil
+	}
+
+	err := h.conn.Close()
+	h.conn = nil
+
+	return err
+}
+
+// encode serializes the entry in the native journal protocol format.
+func (h *hook) encode(e *logrus.Entry) []byte {
+	b := new(bytes.Buffer)
+
+	writeField(b, "MESSAGE", strings.TrimSuffix(e.Message, "\n"))
+	writeField(b, "PRIORITY", strconv.Itoa(priority(e.Level)))
+	writeField(b, "SYSLOG_IDENTIFIER", h.opt.Identifier)
+
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := e.Data[k]
+
+		switch k {
+		case fnField:
+			if fn, ok := v.(string); ok {
+				writeField(b, "CODE_FUNC", fn)
+				continue
+			}
+		case srcField:
+			if src, ok := v.(string); ok {
+				file, line := splitSrc(src)
+				writeField(b, "CODE_FILE", file)
+				if len(line) > 0 {
+					writeField(b, "CODE_LINE", line)
+				}
+
+				continue
+			}
+		}
+
+		writeField(b, fieldName(k), fieldValue(v))
+	}
+
+	return b.Bytes()
+}
+
+// writeField writes the field as NAME=value line, values with newlines
+// are written as NAME line followed by the value length and the value.
+func writeField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+
+	if !strings.ContainsRune(value, '\n') {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+
+		return
+	}
+
+	b.WriteByte('\n')
+
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	b.Write(size[:])
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// fieldName returns the journal field name: upper-cased letters, digits and '_',
+// not starting with '_' or a digit, at most 64 characters.
+func fieldName(k string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, k)
+
+	name = strings.TrimLeft(name, "_")
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') || reservedFields[name] {
+		name = "FIELD_" + name
+	}
+
+	if len(name) > maxFieldNameLen {
+		name = name[:maxFieldNameLen]
+	}
+
+	return name
+}
+
+func fieldValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func splitSrc(src string) (file, line string) {
+	idx := strings.LastIndexByte(src, ':')
+	if idx < 0 {
+		return src, ""
+	}
+
+	if _, err := strconv.Atoi(src[idx+1:]); err != nil {
+		return src, ""
+	}
+
+	return src[:idx], src[idx+1:]
+}
+
+// priority maps logrus levels onto syslog priorities.
+func priority(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return 1 // alert
+	case logrus.FatalLevel:
+		return 2 // critical
+	case logrus.ErrorLevel:
+		return 3 // error
+	case logrus.WarnLevel:
+		return 4 // warning
+	case logrus.InfoLevel:
+		return 6 // informational
+	default:
+		return 7 // debug
+	}
+}
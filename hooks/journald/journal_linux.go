@@ -0,0 +1,92 @@
+package journald
+
+import (
+	"errors"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// send writes the datagram into the journal socket, passing it as sealed memfd
+// when it is too large for a datagram. Must be called with the mutex held.
+func (h *hook) send(data []byte) error {
+	if h.conn == nil {
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{
+			Name: "",
+			Net:  "unixgram",
+		})
+		if err != nil {
+			return err
+		}
+
+		h.conn = conn
+	}
+
+	addr := &net.UnixAddr{
+		Name: h.opt.SocketPath,
+		Net:  "unixgram",
+	}
+
+	_, _, err := h.conn.WriteMsgUnix(data, nil, addr)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, unix.EMSGSIZE) && !errors.Is(err, unix.ENOBUFS) {
+		return err
+	}
+
+	f, err := memFile(data)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, _, err = h.conn.WriteMsgUnix(nil, unix.UnixRights(int(f.Fd())), addr)
+
+	return err
+}
+
+// memFile returns a sealed memfd with the data, falling back to an unlinked
+// temporary file in /dev/shm on kernels without memfd support.
+func memFile(data []byte) (*os.File, error) {
+	fd, err := unix.MemfdCreate("journal-entry", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return tempFile(data)
+	}
+
+	f := os.NewFile(uintptr(fd), "journal-entry")
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, seals); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func tempFile(data []byte) (*os.File, error) {
+	f, err := os.CreateTemp("/dev/shm", "journal-entry-")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(f.Name()); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
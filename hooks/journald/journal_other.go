@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package journald
+
+import "errors"
+
+var errNotSupported = errors.New("journald is supported on Linux only")
+
+func (h *hook) send(data []byte) error {
+	return errNotSupported
+}
@@ -0,0 +1,217 @@
+//go:build linux
+// +build linux
+
+package journald
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	journaldHook "github.com/xlab/suplog/hooks/journald"
+
+	"github.com/xlab/suplog"
+	"github.com/xlab/suplog/suplogtest"
+)
+
+func listen(t *testing.T) (*net.UnixConn, string) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	return conn, path
+}
+
+// receive reads an entry from the socket, reading it from the passed fd if any.
+func receive(t *testing.T, conn *net.UnixConn) map[string]string {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 1<<20)
+	oob := make([]byte, syscall.CmsgSpace(4))
+
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf[:n]
+
+	if oobn > 0 {
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fds, err := syscall.ParseUnixRights(&msgs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f := os.NewFile(uintptr(fds[0]), "journal-entry")
+		defer f.Close()
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+
+		if data, err = io.ReadAll(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return parse(t, data)
+}
+
+func parse(t *testing.T, data []byte) map[string]string {
+	fields := make(map[string]string)
+
+	for len(data) > 0 {
+		idx := bytes.IndexAny(data, "=\n")
+		if idx < 0 {
+			t.Fatalf("malformed entry: %q", data)
+		}
+
+		name := string(data[:idx])
+
+		if data[idx] == '=' {
+			end := bytes.IndexByte(data, '\n')
+			fields[name] = string(data[idx+1 : end])
+			data = data[end+1:]
+
+			continue
+		}
+
+		size := binary.LittleEndian.Uint64(data[idx+1 : idx+9])
+		fields[name] = string(data[idx+9 : idx+9+int(size)])
+		data = data[idx+9+int(size)+1:]
+	}
+
+	return fields
+}
+
+func TestJournaldHook(t *testing.T) {
+	server, path := listen(t)
+
+	hook := journaldHook.NewHook(suplog.DefaultLogger, &journaldHook.HookOptions{
+		SocketPath: path,
+		Identifier: "api",
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Named("db").WithFields(suplog.Fields{
+		"user.id": 42,
+		"message": "clash",
+		"_secret": "x",
+		"src":     "app/repo/repo.go:42",
+		"fn":      "Find",
+		"error":   errors.New("first line\nsecond line"),
+	}).Warning("query failed")
+
+	exp := map[string]string{
+		"MESSAGE":           "query failed",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "api",
+		"CODE_FILE":         "app/repo/repo.go",
+		"CODE_LINE":         "42",
+		"CODE_FUNC":         "Find",
+		"LOGGER":            "db",
+		"USER_ID":           "42",
+		"FIELD_MESSAGE":     "clash",
+		"SECRET":            "x",
+		"ERROR":             "first line\nsecond line",
+	}
+
+	fields := receive(t, server)
+	for k, v := range exp {
+		if fields[k] != v {
+			t.Errorf("expected %s=%q, got %q", k, v, fields[k])
+		}
+	}
+
+	if len(fields) != len(exp) {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+}
+
+func TestJournaldHookLargeEntry(t *testing.T) {
+	server, path := listen(t)
+
+	hook := journaldHook.NewHook(suplog.DefaultLogger, &journaldHook.HookOptions{
+		SocketPath: path,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	payload := strings.Repeat("x", 512*1024)
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.WithField("payload", payload).Error("large entry")
+
+	fields := receive(t, server)
+	if fields["MESSAGE"] != "large entry" || fields["PAYLOAD"] != payload {
+		t.Errorf("unexpected entry: MESSAGE=%q, len(PAYLOAD)=%d", fields["MESSAGE"], len(fields["PAYLOAD"]))
+	}
+}
+
+func TestJournaldHookUnavailable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+
+	// the hook is added to its own logger, so reports fire it again
+	rec := suplogtest.NewRecorder(nil)
+	hook := journaldHook.NewHook(rec, &journaldHook.HookOptions{
+		SocketPath: path,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	rec.AddHook(hook)
+
+	entry := &logrus.Entry{
+		Logger:  logrus.New(),
+		Data:    logrus.Fields{},
+		Time:    time.Now(),
+		Level:   logrus.ErrorLevel,
+		Message: "dropped",
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("expected entry dropped without error, got %v", err)
+		}
+	}
+
+	if reports := rec.Find(suplog.ErrorLevel, "failed to send entry to journald", nil); len(reports) != 1 {
+		t.Errorf("expected the failure reported once, got %d reports", len(reports))
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	entry.Message = "delivered"
+	if err := hook.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if fields := receive(t, conn); fields["MESSAGE"] != "delivered" {
+		t.Errorf("expected entry delivered once journald is available, got %q", fields["MESSAGE"])
+	}
+}
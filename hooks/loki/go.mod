@@ -0,0 +1,12 @@
+module github.com/xlab/suplog/hooks/loki
+
+go 1.16
+
+require (
+	github.com/golang/snappy v0.0.4
+	github.com/sirupsen/logrus v1.9.0
+	github.com/xlab/suplog v1.4.2-0.20261017051833-a3d4ac419e46
+	google.golang.org/protobuf v1.28.1
+)
+
+replace github.com/xlab/suplog => ../../
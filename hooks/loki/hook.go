@@ -0,0 +1,376 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/xlab/suplog/batcher"
+)
+
+// Encoding of push requests.
+type Encoding int
+
+const (
+	// EncodingProtobuf sends snappy-compressed protobuf push requests.
+	EncodingProtobuf Encoding = iota
+	// EncodingJSON sends JSON push requests.
+	EncodingJSON
+)
+
+// LevelLabel is the label name of the entry level, when listed in LabelFields.
+const LevelLabel = "level"
+
+// overflowValue replaces label values exceeding MaxLabelValues.
+const overflowValue = "_overflow"
+
+const (
+	defaultPushPath       = "/loki/api/v1/push"
+	defaultMaxLabelValues = 100
+	defaultBatchSize      = 1 << 20
+	defaultBatchWait      = time.Second
+	defaultQueueSize      = 10000
+	defaultMinBackoff     = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	defaultMaxRetries     = 10
+	defaultTimeout        = 10 * time.Second
+)
+
+// HookOptions allows to set additional Hook options.
+type HookOptions struct {
+	// Levels enables this hook for all listed levels, all levels by default.
+	Levels []logrus.Level
+	// URL is the Loki push endpoint (LOG_LOKI_URL), /loki/api/v1/push is appended
+	// when the URL has no path, e.g. http://loki:3100.
+	URL string
+	// TenantID sets X-Scope-OrgID header of push requests (LOG_LOKI_TENANT_ID).
+	TenantID string
+	// Username and Password enable basic auth.
+	Username string
+	Password string
+	// Encoding of push requests, EncodingProtobuf by default.
+	Encoding Encoding
+	// Labels are added to all streams.
+	Labels map[string]string
+	// LabelFields lists fields promoted to stream labels, env, service and level by default.
+	// Use LevelLabel to label streams by the entry level.
+	LabelFields []string
+	// MaxLabelValues limits distinct values of each label field, further values
+	// are replaced with "_overflow" and kept in the line. 100 by default.
+	MaxLabelValues int
+	// Formatter formats lines, without fields promoted to labels. logrus.JSONFormatter by default.
+	Formatter logrus.Formatter
+	// BatchSize triggers a push once lines of the batch exceed it, 1MiB by default.
+	BatchSize int
+	// BatchWait is the maximum time entries wait in the batch, 1s by default.
+	BatchWait time.Duration
+	// QueueSize limits entries waiting to be batched, newer entries are dropped
+	// when the queue is full. 10000 by default.
+	QueueSize int
+	// MinBackoff and MaxBackoff bound delays between retries, 500ms and 30s by default.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRetries limits retries of a batch, 10 by default.
+	MaxRetries int
+	// Timeout bounds a push request, 10s by default.
+	Timeout time.Duration
+	// HTTPClient is used for push requests, a client with Timeout by default.
+	HTTPClient *http.Client
+}
+
+func checkHookOptions(opt *HookOptions) *HookOptions {
+	if opt == nil {
+		opt = &HookOptions{}
+	}
+
+	if len(opt.Levels) == 0 {
+		opt.Levels = logrus.AllLevels
+	}
+
+	if len(opt.URL) == 0 {
+		opt.URL = os.Getenv("LOG_LOKI_URL")
+	}
+
+	if idx := strings.Index(opt.URL, "://"); idx >= 0 && !strings.Contains(opt.URL[idx+3:], "/") {
+		opt.URL += defaultPushPath
+	}
+
+	if len(opt.TenantID) == 0 {
+		opt.TenantID = os.Getenv("LOG_LOKI_TENANT_ID")
+	}
+
+	if opt.LabelFields == nil {
+		opt.LabelFields = []string{"env", "service", LevelLabel}
+	}
+
+	if opt.MaxLabelValues <= 0 {
+		opt.MaxLabelValues = defaultMaxLabelValues
+	}
+
+	if opt.Formatter == nil {
+		opt.Formatter = &logrus.JSONFormatter{}
+	}
+
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = defaultBatchSize
+	}
+
+	if opt.BatchWait <= 0 {
+		opt.BatchWait = defaultBatchWait
+	}
+
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = defaultQueueSize
+	}
+
+	if opt.MinBackoff <= 0 {
+		opt.MinBackoff = defaultMinBackoff
+	}
+
+	if opt.MaxBackoff <= 0 {
+		opt.MaxBackoff = defaultMaxBackoff
+	}
+
+	if opt.MaxRetries <= 0 {
+		opt.MaxRetries = defaultMaxRetries
+	}
+
+	if opt.Timeout <= 0 {
+		opt.Timeout = defaultTimeout
+	}
+
+	if opt.HTTPClient == nil {
+		opt.HTTPClient = &http.Client{
+			Timeout: opt.Timeout,
+		}
+	}
+
+	return opt
+}
+
+type RootLogger interface {
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Printf(format string, args ...interface{})
+}
+
+// NewHook initializes a new logrus.Hook that pushes entries to Grafana Loki.
+// Entries are batched and pushed in background, failed pushes are retried
+// with backoff. The hook implements Flush and Close, called on suplog Close.
+func NewHook(logger RootLogger, opt *HookOptions) logrus.Hook {
+	opt = checkHookOptions(opt)
+
+	labelFields := make(map[string]string, len(opt.LabelFields))
+	for _, k := range opt.LabelFields {
+		labelFields[k] = labelName(k)
+	}
+
+	h := &hook{
+		opt:         opt,
+		logger:      logger,
+		labelFields: labelFields,
+		labelValues: make(map[string]map[string]struct{}, len(labelFields)),
+		current:     newBatch(),
+	}
+
+	h.batcher = batcher.New(batcher.Options{
+		QueueSize:     opt.QueueSize,
+		FlushInterval: opt.BatchWait,
+		Add:           h.add,
+		Export:        h.export,
+		Dropped:       h.reportDropped,
+	})
+
+	return h
+}
+
+type hook struct {
+	opt    *HookOptions
+	logger RootLogger
+
+	// labelFields maps promoted fields onto label names
+	labelFields map[string]string
+
+	labelsMux   sync.Mutex
+	labelValues map[string]map[string]struct{}
+	overflowed  map[string]bool
+
+	batcher *batcher.Batcher
+	// current is the batch of the background goroutine
+	current *batch
+}
+
+// message is an entry along with its stream labels.
+type message struct {
+	labels map[string]string
+	entry  entry
+}
+
+type entry struct {
+	ts   time.Time
+	line string
+}
+
+func (h *hook) Levels() []logrus.Level {
+	return h.opt.Levels
+}
+
+func (h *hook) Fire(e *logrus.Entry) error {
+	if h.batcher.Closed() {
+		return nil
+	}
+
+	labels := make(map[string]string, len(h.opt.Labels)+len(h.labelFields))
+	for k, v := range h.opt.Labels {
+		labels[labelName(k)] = v
+	}
+
+	line := e.Dup()
+	line.Level = e.Level
+	line.Message = e.Message
+	line.Caller = e.Caller
+
+	for field, name := range h.labelFields {
+		var value string
+
+		if field == LevelLabel {
+			value = e.Level.String()
+		} else if v, ok := e.Data[field]; ok {
+			value = fmt.Sprint(v)
+		} else {
+			continue
+		}
+
+		if !h.allowLabelValue(name, value) {
+			labels[name] = overflowValue
+			continue
+		}
+
+		labels[name] = value
+		delete(line.Data, field)
+	}
+
+	serialized, err := h.opt.Formatter.Format(line)
+	if err != nil {
+		return fmt.Errorf("failed to format entry for Loki: %w", err)
+	}
+
+	msg := message{
+		labels: labels,
+		entry: entry{
+			ts:   e.Time,
+			line: strings.TrimSuffix(string(serialized), "\n"),
+		},
+	}
+
+	h.batcher.Enqueue(msg)
+
+	return nil
+}
+
+// allowLabelValue reports whether the value can be used as label value,
+// keeping the number of distinct values of each label under MaxLabelValues.
+func (h *hook) allowLabelValue(name, value string) bool {
+	h.labelsMux.Lock()
+
+	values, ok := h.labelValues[name]
+	if !ok {
+		values = make(map[string]struct{})
+		h.labelValues[name] = values
+	}
+
+	if _, ok := values[value]; ok {
+		h.labelsMux.Unlock()
+		return true
+	}
+
+	if len(values) < h.opt.MaxLabelValues {
+		values[value] = struct{}{}
+		h.labelsMux.Unlock()
+		return true
+	}
+
+	report := !h.overflowed[name]
+	if report {
+		if h.overflowed == nil {
+			h.overflowed = make(map[string]bool)
+		}
+
+		h.overflowed[name] = true
+	}
+
+	h.labelsMux.Unlock()
+
+	if report {
+		// the hook may fire again for this entry, so it's logged without holding the lock
+		h.logger.Errorf("failed to promote %s to Loki label, more than %d values, %s is used instead",
+			name, h.opt.MaxLabelValues, overflowValue)
+	}
+
+	return false
+}
+
+// Flush blocks until entries queued before the call are pushed, or ctx is done.
+func (h *hook) Flush(ctx context.Context) error {
+	return h.batcher.Flush(ctx)
+}
+
+// Close pushes queued entries and stops the hook.
+func (h *hook) Close(ctx context.Context) error {
+	return h.batcher.Close(ctx)
+}
+
+// labelName returns the valid Prometheus label name, replacing other characters with '_'.
+func labelName(k string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, k)
+
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+
+	return name
+}
+
+// labelsString formats labels as {name="value", ...}, sorted by name.
+func labelsString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var b strings.Builder
+
+	b.WriteByte('{')
+
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+
+	b.WriteByte('}')
+
+	return b.String()
+}
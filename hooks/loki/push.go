@@ -0,0 +1,239 @@
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/xlab/suplog/batcher"
+)
+
+// batch groups entries by streams, keeping the order of streams.
+type batch struct {
+	streams []*stream
+	index   map[string]*stream
+	size    int
+}
+
+type stream struct {
+	labels  map[string]string
+	key     string
+	entries []entry
+}
+
+func newBatch() *batch {
+	return &batch{
+		index: make(map[string]*stream),
+	}
+}
+
+func (b *batch) add(msg message) {
+	key := labelsString(msg.labels)
+
+	s, ok := b.index[key]
+	if !ok {
+		s = &stream{
+			labels: msg.labels,
+			key:    key,
+		}
+
+		b.index[key] = s
+		b.streams = append(b.streams, s)
+	}
+
+	s.entries = append(s.entries, msg.entry)
+	b.size += len(msg.entry.line)
+}
+
+func (b *batch) empty() bool {
+	return len(b.streams) == 0
+}
+
+// add adds the queued entry to the current batch, reporting whether the batch is full.
+func (h *hook) add(item interface{}) bool {
+	h.current.add(item.(message))
+
+	return h.current.size >= h.opt.BatchSize
+}
+
+// export pushes the current batch, if not empty.
+func (h *hook) export() {
+	if !h.current.empty() {
+		h.push(h.current)
+		h.current = newBatch()
+	}
+}
+
+// push sends the batch, retrying with backoff on network errors, 429 and 5xx responses.
+func (h *hook) push(b *batch) {
+	body, contentType, err := h.encode(b)
+	if err != nil {
+		h.logger.Errorf("failed to encode Loki push request: %v", err)
+		return
+	}
+
+	backoff := &batcher.Backoff{
+		Min: h.opt.MinBackoff,
+		Max: h.opt.MaxBackoff,
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, err := h.send(body, contentType)
+		if err == nil {
+			return
+		}
+
+		if !retry || attempt == h.opt.MaxRetries {
+			h.logger.Errorf("failed to push %d entries to Loki: %v", b.len(), err)
+			return
+		}
+
+		if !h.batcher.Wait(backoff.Next()) {
+			return
+		}
+	}
+}
+
+func (b *batch) len() int {
+	n := 0
+	for _, s := range b.streams {
+		n += len(s.entries)
+	}
+
+	return n
+}
+
+// send performs the push request, reporting whether it should be retried on error.
+func (h *hook) send(body []byte, contentType string) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.opt.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.opt.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	if len(h.opt.TenantID) > 0 {
+		req.Header.Set("X-Scope-OrgID", h.opt.TenantID)
+	}
+
+	if len(h.opt.Username) > 0 {
+		req.SetBasicAuth(h.opt.Username, h.opt.Password)
+	}
+
+	resp, err := h.opt.HTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("server returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5, err
+}
+
+// reportDropped reports entries dropped due to the full queue.
+func (h *hook) reportDropped(n uint64) {
+	h.logger.Errorf("failed to push %d entries to Loki, queue is full", n)
+}
+
+func (h *hook) encode(b *batch) ([]byte, string, error) {
+	if h.opt.Encoding == EncodingJSON {
+		body, err := encodeJSON(b)
+		return body, "application/json", err
+	}
+
+	return encodeProtobuf(b), "application/x-protobuf", nil
+}
+
+type jsonPushRequest struct {
+	Streams []jsonStream `json:"streams"`
+}
+
+type jsonStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// encodeJSON encodes the batch as JSON push request.
+func encodeJSON(b *batch) ([]byte, error) {
+	req := jsonPushRequest{
+		Streams: make([]jsonStream, 0, len(b.streams)),
+	}
+
+	for _, s := range b.streams {
+		values := make([][2]string, 0, len(s.entries))
+		for _, e := range s.entries {
+			values = append(values, [2]string{
+				strconv.FormatInt(e.ts.UnixNano(), 10),
+				e.line,
+			})
+		}
+
+		req.Streams = append(req.Streams, jsonStream{
+			Stream: s.labels,
+			Values: values,
+		})
+	}
+
+	return json.Marshal(req)
+}
+
+// Field numbers of logproto.PushRequest messages.
+const (
+	pushRequestStreams = 1
+	streamLabels       = 1
+	streamEntries      = 2
+	entryTimestamp     = 1
+	entryLine          = 2
+	timestampSeconds   = 1
+	timestampNanos     = 2
+)
+
+// encodeProtobuf encodes the batch as snappy-compressed logproto.PushRequest.
+func encodeProtobuf(b *batch) []byte {
+	var req, s, e, ts []byte
+
+	for _, stream := range b.streams {
+		s = s[:0]
+		s = protowire.AppendTag(s, streamLabels, protowire.BytesType)
+		s = protowire.AppendString(s, stream.key)
+
+		for _, entry := range stream.entries {
+			ts = ts[:0]
+			ts = protowire.AppendTag(ts, timestampSeconds, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(entry.ts.Unix()))
+			ts = protowire.AppendTag(ts, timestampNanos, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(entry.ts.Nanosecond()))
+
+			e = e[:0]
+			e = protowire.AppendTag(e, entryTimestamp, protowire.BytesType)
+			e = protowire.AppendBytes(e, ts)
+			e = protowire.AppendTag(e, entryLine, protowire.BytesType)
+			e = protowire.AppendString(e, entry.line)
+
+			s = protowire.AppendTag(s, streamEntries, protowire.BytesType)
+			s = protowire.AppendBytes(s, e)
+		}
+
+		req = protowire.AppendTag(req, pushRequestStreams, protowire.BytesType)
+		req = protowire.AppendBytes(req, s)
+	}
+
+	return snappy.Encode(nil, req)
+}
@@ -0,0 +1,255 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	lokiHook "github.com/xlab/suplog/hooks/loki"
+	"github.com/xlab/suplog/suplogtest"
+
+	"github.com/xlab/suplog"
+)
+
+type jsonPushRequest struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func TestLokiHookJSON(t *testing.T) {
+	srv := suplogtest.NewHTTPServer(t, "/loki/api/v1/push", http.StatusNoContent)
+
+	hook := lokiHook.NewHook(suplog.DefaultLogger, &lokiHook.HookOptions{
+		URL:      srv.URL,
+		TenantID: "team-a",
+		Encoding: lokiHook.EncodingJSON,
+		Labels:   map[string]string{"app": "api"},
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.WithField("env", "prod").WithField("user", "max").Warning("query failed")
+	logger.WithField("env", "prod").Info("ok")
+
+	suplogtest.FlushHook(t, hook)
+
+	requests, bodies := srv.Received()
+	if len(requests) != 1 {
+		t.Fatalf("expected a single push request, got %d", len(requests))
+	}
+
+	if requests[0].Header.Get("X-Scope-OrgID") != "team-a" {
+		t.Errorf("expected tenant header, got %v", requests[0].Header)
+	}
+
+	var req jsonPushRequest
+	if err := json.Unmarshal(bodies[0], &req); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(req.Streams) != 2 {
+		t.Fatalf("expected streams per level, got %s", bodies[0])
+	}
+
+	stream := req.Streams[0]
+	if stream.Stream["app"] != "api" || stream.Stream["env"] != "prod" || stream.Stream["level"] != "warning" {
+		t.Errorf("unexpected labels: %v", stream.Stream)
+	}
+
+	var line map[string]interface{}
+	if err := json.Unmarshal([]byte(stream.Values[0][1]), &line); err != nil {
+		t.Fatal(err)
+	}
+
+	if line["msg"] != "query failed" || line["user"] != "max" {
+		t.Errorf("unexpected line: %s", stream.Values[0][1])
+	}
+
+	if _, ok := line["env"]; ok {
+		t.Errorf("expected env promoted to label, got line %s", stream.Values[0][1])
+	}
+}
+
+func TestLokiHookProtobuf(t *testing.T) {
+	srv := suplogtest.NewHTTPServer(t, "/loki/api/v1/push", http.StatusNoContent)
+
+	hook := lokiHook.NewHook(suplog.DefaultLogger, &lokiHook.HookOptions{
+		URL:         srv.URL,
+		LabelFields: []string{"service"},
+		Formatter:   &suplog.LogfmtFormatter{DisableTimestamp: true},
+	})
+
+	ts := time.Unix(1622545200, 42)
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.WithField("service", "api").WithTime(ts).Info("started")
+
+	if err := hook.(suplog.Closer).Close(context.Background()); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	requests, bodies := srv.Received()
+	if len(requests) != 1 || requests[0].Header.Get("Content-Type") != "application/x-protobuf" {
+		t.Fatalf("expected a single protobuf push request, got %d", len(requests))
+	}
+
+	data, err := snappy.Decode(nil, bodies[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streams := fields(t, data)[1]
+	if len(streams) != 1 {
+		t.Fatalf("expected a single stream, got %d", len(streams))
+	}
+
+	stream := fields(t, streams[0])
+	if labels := string(stream[1][0]); labels != `{service="api"}` {
+		t.Errorf("unexpected labels: %s", labels)
+	}
+
+	entry := fields(t, stream[2][0])
+	if line := string(entry[2][0]); line != "level=info msg=started" {
+		t.Errorf("unexpected line: %s", line)
+	}
+
+	timestamp := fields(t, entry[1][0])
+	if seconds, _ := protowire.ConsumeVarint(timestamp[1][0]); seconds != 1622545200 {
+		t.Errorf("unexpected timestamp seconds: %d", seconds)
+	}
+
+	if nanos, _ := protowire.ConsumeVarint(timestamp[2][0]); nanos != 42 {
+		t.Errorf("unexpected timestamp nanos: %d", nanos)
+	}
+}
+
+// fields decodes protobuf message fields, varints are kept encoded.
+func fields(t *testing.T, b []byte) map[protowire.Number][][]byte {
+	result := make(map[protowire.Number][][]byte)
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+
+		b = b[n:]
+
+		var value []byte
+
+		switch typ {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			_, n = protowire.ConsumeVarint(b)
+			if n >= 0 {
+				value = b[:n]
+			}
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+
+		result[num] = append(result[num], value)
+		b = b[n:]
+	}
+
+	return result
+}
+
+func TestLokiHookRetry(t *testing.T) {
+	srv := suplogtest.NewHTTPServer(t, "/loki/api/v1/push", http.StatusNoContent)
+	srv.Fail(2, nil)
+
+	hook := lokiHook.NewHook(suplog.DefaultLogger, &lokiHook.HookOptions{
+		URL:        srv.URL,
+		Encoding:   lokiHook.EncodingJSON,
+		MinBackoff: 10 * time.Millisecond,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("retried")
+
+	suplogtest.FlushHook(t, hook)
+
+	if _, bodies := srv.Received(); len(bodies) != 1 || !strings.Contains(string(bodies[0]), "retried") {
+		t.Errorf("expected the entry pushed after retries, got %q", bodies)
+	}
+}
+
+func TestLokiHookCardinalityGuard(t *testing.T) {
+	srv := suplogtest.NewHTTPServer(t, "/loki/api/v1/push", http.StatusNoContent)
+
+	rec := suplogtest.NewRecorder(nil)
+
+	hook := lokiHook.NewHook(rec, &lokiHook.HookOptions{
+		URL:            srv.URL,
+		Encoding:       lokiHook.EncodingJSON,
+		LabelFields:    []string{"user"},
+		MaxLabelValues: 2,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	for _, user := range []string{"a", "b", "c", "a"} {
+		logger.WithField("user", user).Info("login")
+	}
+
+	suplogtest.FlushHook(t, hook)
+
+	_, bodies := srv.Received()
+
+	var req jsonPushRequest
+	if err := json.Unmarshal(bodies[0], &req); err != nil {
+		t.Fatal(err)
+	}
+
+	users := make(map[string]int)
+	for _, stream := range req.Streams {
+		users[stream.Stream["user"]] += len(stream.Values)
+
+		if stream.Stream["user"] == "_overflow" && !strings.Contains(stream.Values[0][1], `"user":"c"`) {
+			t.Errorf("expected overflowed value kept in the line, got %s", stream.Values[0][1])
+		}
+	}
+
+	if len(users) != 3 || users["a"] != 2 || users["b"] != 1 || users["_overflow"] != 1 {
+		t.Errorf("unexpected streams: %v", users)
+	}
+
+	if reports := rec.Find(suplog.ErrorLevel, "failed to promote user to Loki label", nil); len(reports) != 1 {
+		t.Errorf("expected the overflow reported once, got %d reports", len(reports))
+	}
+}
+
+func TestLokiHookPushFailure(t *testing.T) {
+	srv := suplogtest.NewHTTPServer(t, "/loki/api/v1/push", http.StatusBadRequest)
+
+	rec := suplogtest.NewRecorder(nil)
+
+	hook := lokiHook.NewHook(rec, &lokiHook.HookOptions{
+		URL:      srv.URL,
+		Encoding: lokiHook.EncodingJSON,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("lost")
+
+	suplogtest.FlushHook(t, hook)
+
+	rec.AssertLogged(t, suplog.ErrorLevel, "failed to push 1 entries to Loki", nil)
+}
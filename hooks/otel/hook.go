@@ -0,0 +1,134 @@
+package otel
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Field names added to entries logged within a span.
+const (
+	TraceIDField    = "trace_id"
+	SpanIDField     = "span_id"
+	TraceFlagsField = "trace_flags"
+)
+
+// HookOptions allows to set additional Hook options.
+type HookOptions struct {
+	// Levels enables this hook for all listed levels, all levels by default.
+	Levels []logrus.Level
+	// RecordErrors enables recording of entries on ErrorLevels as span events
+	// on the active span. Entries with an error are recorded as exceptions.
+	RecordErrors bool
+	// ErrorLevels lists levels recorded as span events, Error, Fatal and Panic by default.
+	ErrorLevels []logrus.Level
+}
+
+func checkHookOptions(opt *HookOptions) *HookOptions {
+	if opt == nil {
+		opt = &HookOptions{}
+	}
+
+	if len(opt.Levels) == 0 {
+		opt.Levels = logrus.AllLevels
+	}
+
+	if len(opt.ErrorLevels) == 0 {
+		opt.ErrorLevels = []logrus.Level{
+			logrus.PanicLevel,
+			logrus.FatalLevel,
+			logrus.ErrorLevel,
+		}
+	}
+
+	return opt
+}
+
+type RootLogger interface {
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Printf(format string, args ...interface{})
+}
+
+// NewHook initializes a new logrus.Hook that correlates entries with traces,
+// reading the span context from the entry context (see suplog.WithContext).
+func NewHook(logger RootLogger, opt *HookOptions) logrus.Hook {
+	opt = checkHookOptions(opt)
+
+	errorLevels := make(map[logrus.Level]bool, len(opt.ErrorLevels))
+	for _, level := range opt.ErrorLevels {
+		errorLevels[level] = true
+	}
+
+	return &hook{
+		opt:         opt,
+		logger:      logger,
+		errorLevels: errorLevels,
+	}
+}
+
+type hook struct {
+	opt         *HookOptions
+	logger      RootLogger
+	errorLevels map[logrus.Level]bool
+}
+
+func (h *hook) Levels() []logrus.Level {
+	return h.opt.Levels
+}
+
+// logEventName is the name of span events recorded for entries without an error.
+const logEventName = "log"
+
+func (h *hook) Fire(e *logrus.Entry) error {
+	if e.Context == nil {
+		return nil
+	}
+
+	span := trace.SpanFromContext(e.Context)
+	spanCtx := span.SpanContext()
+	if !spanCtx.IsValid() {
+		return nil
+	}
+
+	e.Data[TraceIDField] = spanCtx.TraceID().String()
+	e.Data[SpanIDField] = spanCtx.SpanID().String()
+	e.Data[TraceFlagsField] = spanCtx.TraceFlags().String()
+
+	if !h.opt.RecordErrors || !h.errorLevels[e.Level] || !span.IsRecording() {
+		return nil
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(e.Data)+2)
+	attrs = append(attrs,
+		attribute.String("log.severity", e.Level.String()),
+		attribute.String("log.message", e.Message),
+	)
+
+	var err error
+
+	for k, v := range e.Data {
+		switch k {
+		case TraceIDField, SpanIDField, TraceFlagsField:
+			continue
+		case logrus.ErrorKey:
+			if vErr, ok := v.(error); ok {
+				err = vErr
+				continue
+			}
+		}
+
+		attrs = append(attrs, attribute.String(k, fmt.Sprint(v)))
+	}
+
+	if err != nil {
+		span.RecordError(err, trace.WithAttributes(attrs...))
+	} else {
+		span.AddEvent(logEventName, trace.WithAttributes(attrs...))
+	}
+
+	return nil
+}
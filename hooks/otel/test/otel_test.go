@@ -0,0 +1,77 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	otelHook "github.com/xlab/suplog/hooks/otel"
+
+	"github.com/xlab/suplog"
+)
+
+func TestOtelHook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "op")
+
+	out := new(bytes.Buffer)
+	logger := suplog.NewLogger(
+		out,
+		new(suplog.JSONFormatter),
+		otelHook.NewHook(suplog.DefaultLogger, &otelHook.HookOptions{
+			RecordErrors: true,
+		}),
+	)
+
+	logger.WithContext(ctx).Info("within span")
+	logger.WithContext(ctx).WithField("host", "db").Error("query failed")
+	logger.WithContext(ctx).WithError(errors.New("timeout")).Error("request failed")
+	logger.Info("without span")
+
+	span.End()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got: %s", out.String())
+	}
+
+	spanCtx := span.SpanContext()
+	for _, field := range []string{
+		`"trace_id":"` + spanCtx.TraceID().String() + `"`,
+		`"span_id":"` + spanCtx.SpanID().String() + `"`,
+		`"trace_flags":"01"`,
+	} {
+		if !strings.Contains(lines[0], field) {
+			t.Errorf("expected entry with %s, got %s", field, lines[0])
+		}
+	}
+
+	if strings.Contains(lines[3], otelHook.TraceIDField) {
+		t.Errorf("expected entry without trace fields, got %s", lines[3])
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	events := spans[0].Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 span events, got %d", len(events))
+	}
+
+	if events[0].Name != "log" {
+		t.Errorf("expected log event, got %s", events[0].Name)
+	}
+
+	if events[1].Name != "exception" {
+		t.Errorf("expected exception event, got %s", events[1].Name)
+	}
+}
@@ -0,0 +1,351 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/xlab/suplog/batcher"
+)
+
+// batch groups records by instrumentation scopes, keeping the order of scopes.
+type batch struct {
+	scopes []*logspb.ScopeLogs
+	index  map[string]*logspb.ScopeLogs
+	size   int
+}
+
+func newBatch() *batch {
+	return &batch{
+		index: make(map[string]*logspb.ScopeLogs),
+	}
+}
+
+func (b *batch) add(msg message) {
+	s, ok := b.index[msg.scope]
+	if !ok {
+		s = &logspb.ScopeLogs{
+			Scope: &commonpb.InstrumentationScope{
+				Name: msg.scope,
+			},
+		}
+
+		b.index[msg.scope] = s
+		b.scopes = append(b.scopes, s)
+	}
+
+	s.LogRecords = append(s.LogRecords, msg.record)
+	b.size++
+}
+
+// add adds the queued record to the current batch, reporting whether the batch is full.
+func (h *hook) add(item interface{}) bool {
+	h.current.add(item.(message))
+
+	return h.current.size >= h.opt.BatchSize
+}
+
+// export sends the current batch, if not empty.
+func (h *hook) export() {
+	if h.current.size > 0 {
+		h.exportBatch(h.current)
+		h.current = newBatch()
+	}
+}
+
+// exportBatch sends the batch, retrying with backoff on network errors, 429, 502, 503 and 504 responses.
+func (h *hook) exportBatch(b *batch) {
+	req := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource:  h.resource,
+			ScopeLogs: b.scopes,
+		}},
+	}
+
+	body, contentType, err := h.encode(req)
+	if err != nil {
+		h.logger.Errorf("failed to encode OTLP export request: %v", err)
+		return
+	}
+
+	backoff := &batcher.Backoff{
+		Min: h.opt.MinBackoff,
+		Max: h.opt.MaxBackoff,
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, delay, err := h.send(body, contentType)
+		if err == nil {
+			return
+		}
+
+		if !retry || attempt == h.opt.MaxRetries {
+			h.logger.Errorf("failed to export %d records to OTLP: %v", b.size, err)
+			return
+		}
+
+		if delay <= 0 {
+			delay = backoff.Next()
+		}
+
+		if !h.batcher.Wait(delay) {
+			return
+		}
+	}
+}
+
+// send performs the export request, reporting whether it should be retried on error,
+// and the delay requested by the collector with Retry-After on 429 and 503 responses.
+func (h *hook) send(body []byte, contentType string) (retry bool, delay time.Duration, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.opt.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.opt.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, err
+	}
+
+	for k, v := range h.opt.Headers {
+		req.Header.Set(k, v)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := h.opt.HTTPClient.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		h.reportPartialSuccess(respBody, resp.Header.Get("Content-Type"))
+
+		return false, 0, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("server returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true, retryAfter(resp.Header.Get("Retry-After"), time.Now()), err
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true, 0, err
+	}
+
+	return false, 0, err
+}
+
+// retryAfter parses the Retry-After header, either delay seconds or HTTP date.
+// Returns 0 if the header is missing or invalid.
+func retryAfter(v string, now time.Time) time.Duration {
+	if len(v) == 0 {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
+
+// reportDropped reports records dropped due to the full queue.
+func (h *hook) reportDropped(n uint64) {
+	h.logger.Errorf("failed to export %d records to OTLP, queue is full", n)
+}
+
+// reportPartialSuccess reports records rejected by the collector, if any.
+func (h *hook) reportPartialSuccess(body []byte, contentType string) {
+	if len(body) == 0 {
+		return
+	}
+
+	var (
+		rejected int64
+		errMsg   string
+	)
+
+	if contentType == "application/json" {
+		var resp jsonExportResponse
+		if err := json.Unmarshal(body, &resp); err != nil || resp.PartialSuccess == nil {
+			return
+		}
+
+		rejected, _ = resp.PartialSuccess.RejectedLogRecords.Int64()
+		errMsg = resp.PartialSuccess.ErrorMessage
+	} else {
+		var resp collogspb.ExportLogsServiceResponse
+		if err := proto.Unmarshal(body, &resp); err != nil || resp.PartialSuccess == nil {
+			return
+		}
+
+		rejected = resp.PartialSuccess.RejectedLogRecords
+		errMsg = resp.PartialSuccess.ErrorMessage
+	}
+
+	if rejected > 0 {
+		h.logger.Errorf("failed to export %d records to OTLP, rejected: %s", rejected, errMsg)
+	} else if len(errMsg) > 0 {
+		h.logger.Warningf("OTLP export partially succeeded: %s", errMsg)
+	}
+}
+
+func (h *hook) encode(req *collogspb.ExportLogsServiceRequest) ([]byte, string, error) {
+	if h.opt.Encoding == EncodingJSON {
+		body, err := json.Marshal(newJSONRequest(req))
+		return body, "application/json", err
+	}
+
+	body, err := proto.Marshal(req)
+	return body, "application/x-protobuf", err
+}
+
+// JSON encoding of OTLP messages differs from the standard protobuf JSON mapping:
+// trace and span ids are hex-encoded, and enums are encoded as integers.
+
+type jsonExportRequest struct {
+	ResourceLogs []jsonResourceLogs `json:"resourceLogs"`
+}
+
+type jsonExportResponse struct {
+	PartialSuccess *struct {
+		RejectedLogRecords json.Number `json:"rejectedLogRecords"`
+		ErrorMessage       string      `json:"errorMessage"`
+	} `json:"partialSuccess"`
+}
+
+type jsonResourceLogs struct {
+	Resource  jsonResource    `json:"resource"`
+	ScopeLogs []jsonScopeLogs `json:"scopeLogs"`
+}
+
+type jsonResource struct {
+	Attributes []jsonKeyValue `json:"attributes,omitempty"`
+}
+
+type jsonScopeLogs struct {
+	Scope      jsonScope       `json:"scope"`
+	LogRecords []jsonLogRecord `json:"logRecords"`
+}
+
+type jsonScope struct {
+	Name string `json:"name"`
+}
+
+type jsonLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int32          `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 jsonAnyValue   `json:"body"`
+	Attributes           []jsonKeyValue `json:"attributes,omitempty"`
+	Flags                uint32         `json:"flags,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type jsonKeyValue struct {
+	Key   string       `json:"key"`
+	Value jsonAnyValue `json:"value"`
+}
+
+type jsonAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func newJSONRequest(req *collogspb.ExportLogsServiceRequest) *jsonExportRequest {
+	out := &jsonExportRequest{
+		ResourceLogs: make([]jsonResourceLogs, 0, len(req.ResourceLogs)),
+	}
+
+	for _, rl := range req.ResourceLogs {
+		resourceLogs := jsonResourceLogs{
+			Resource: jsonResource{
+				Attributes: newJSONAttributes(rl.Resource.GetAttributes()),
+			},
+			ScopeLogs: make([]jsonScopeLogs, 0, len(rl.ScopeLogs)),
+		}
+
+		for _, sl := range rl.ScopeLogs {
+			scopeLogs := jsonScopeLogs{
+				Scope: jsonScope{
+					Name: sl.Scope.GetName(),
+				},
+				LogRecords: make([]jsonLogRecord, 0, len(sl.LogRecords)),
+			}
+
+			for _, r := range sl.LogRecords {
+				scopeLogs.LogRecords = append(scopeLogs.LogRecords, jsonLogRecord{
+					TimeUnixNano:         strconv.FormatUint(r.TimeUnixNano, 10),
+					ObservedTimeUnixNano: strconv.FormatUint(r.ObservedTimeUnixNano, 10),
+					SeverityNumber:       int32(r.SeverityNumber),
+					SeverityText:         r.SeverityText,
+					Body:                 newJSONValue(r.Body),
+					Attributes:           newJSONAttributes(r.Attributes),
+					Flags:                r.Flags,
+					TraceID:              hex.EncodeToString(r.TraceId),
+					SpanID:               hex.EncodeToString(r.SpanId),
+				})
+			}
+
+			resourceLogs.ScopeLogs = append(resourceLogs.ScopeLogs, scopeLogs)
+		}
+
+		out.ResourceLogs = append(out.ResourceLogs, resourceLogs)
+	}
+
+	return out
+}
+
+func newJSONAttributes(attrs []*commonpb.KeyValue) []jsonKeyValue {
+	out := make([]jsonKeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		out = append(out, jsonKeyValue{
+			Key:   kv.Key,
+			Value: newJSONValue(kv.Value),
+		})
+	}
+
+	return out
+}
+
+func newJSONValue(v *commonpb.AnyValue) jsonAnyValue {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_BoolValue:
+		return jsonAnyValue{BoolValue: &v.BoolValue}
+	case *commonpb.AnyValue_IntValue:
+		s := strconv.FormatInt(v.IntValue, 10)
+		return jsonAnyValue{IntValue: &s}
+	case *commonpb.AnyValue_DoubleValue:
+		return jsonAnyValue{DoubleValue: &v.DoubleValue}
+	case *commonpb.AnyValue_StringValue:
+		return jsonAnyValue{StringValue: &v.StringValue}
+	}
+
+	empty := ""
+	return jsonAnyValue{StringValue: &empty}
+}
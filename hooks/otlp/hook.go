@@ -0,0 +1,458 @@
+package otlp
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/xlab/suplog/batcher"
+)
+
+// Encoding of export requests.
+type Encoding int
+
+const (
+	// EncodingProtobuf sends binary protobuf export requests.
+	EncodingProtobuf Encoding = iota
+	// EncodingJSON sends JSON-encoded protobuf export requests.
+	EncodingJSON
+)
+
+// ScopeName is the instrumentation scope of entries logged by the root logger,
+// entries of named loggers are scoped by the logger name.
+const ScopeName = "github.com/xlab/suplog"
+
+// Field names of other hooks, mapped onto dedicated record fields and semantic attributes.
+const (
+	loggerField     = "logger"
+	fnField         = "fn"
+	srcField        = "src"
+	traceIDField    = "trace_id"
+	spanIDField     = "span_id"
+	traceFlagsField = "trace_flags"
+)
+
+const (
+	defaultEndpoint      = "http://localhost:4318/v1/logs"
+	defaultBatchSize     = 512
+	defaultFlushInterval = time.Second
+	defaultQueueSize     = 2048
+	defaultMinBackoff    = 500 * time.Millisecond
+	defaultMaxBackoff    = 30 * time.Second
+	defaultMaxRetries    = 10
+	defaultTimeout       = 10 * time.Second
+)
+
+// HookOptions allows to set additional Hook options.
+type HookOptions struct {
+	// Levels enables this hook for all listed levels, all levels by default.
+	Levels []logrus.Level
+	// Endpoint is the OTLP/HTTP logs endpoint (LOG_OTLP_ENDPOINT), http://localhost:4318/v1/logs
+	// by default. OTEL_EXPORTER_OTLP_LOGS_ENDPOINT and OTEL_EXPORTER_OTLP_ENDPOINT are respected.
+	Endpoint string
+	// Encoding of export requests, EncodingProtobuf by default.
+	Encoding Encoding
+	// Headers are added to export requests, e.g. for authentication.
+	Headers map[string]string
+
+	// Env sets deployment.environment resource attribute (APP_ENV), local by default.
+	Env string
+	// AppVersion sets service.version resource attribute (APP_VERSION).
+	AppVersion string
+	// ServiceName sets service.name resource attribute (OTEL_SERVICE_NAME),
+	// the executable name by default.
+	ServiceName string
+	// ResourceAttributes are added to the resource.
+	ResourceAttributes map[string]string
+
+	// BatchSize triggers an export once the batch has that many records, 512 by default.
+	BatchSize int
+	// FlushInterval is the maximum time records wait in the batch, 1s by default.
+	FlushInterval time.Duration
+	// QueueSize limits records waiting to be batched, newer records are dropped
+	// when the queue is full. 2048 by default.
+	QueueSize int
+	// MinBackoff and MaxBackoff bound delays between retries, 500ms and 30s by default.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRetries limits retries of a batch, 10 by default.
+	MaxRetries int
+	// Timeout bounds an export request, 10s by default.
+	Timeout time.Duration
+	// HTTPClient is used for export requests, a client with Timeout by default.
+	HTTPClient *http.Client
+}
+
+func checkHookOptions(opt *HookOptions) *HookOptions {
+	if opt == nil {
+		opt = &HookOptions{}
+	}
+
+	if len(opt.Levels) == 0 {
+		opt.Levels = logrus.AllLevels
+	}
+
+	if len(opt.Endpoint) == 0 {
+		opt.Endpoint = endpointFromEnv()
+	}
+
+	if len(opt.Env) == 0 {
+		opt.Env = os.Getenv("APP_ENV")
+		if len(opt.Env) == 0 {
+			opt.Env = "local"
+		}
+	}
+
+	if len(opt.AppVersion) == 0 {
+		opt.AppVersion = os.Getenv("APP_VERSION")
+	}
+
+	if len(opt.ServiceName) == 0 {
+		opt.ServiceName = os.Getenv("OTEL_SERVICE_NAME")
+		if len(opt.ServiceName) == 0 {
+			opt.ServiceName = filepath.Base(os.Args[0])
+		}
+	}
+
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = defaultBatchSize
+	}
+
+	if opt.FlushInterval <= 0 {
+		opt.FlushInterval = defaultFlushInterval
+	}
+
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = defaultQueueSize
+	}
+
+	if opt.MinBackoff <= 0 {
+		opt.MinBackoff = defaultMinBackoff
+	}
+
+	if opt.MaxBackoff <= 0 {
+		opt.MaxBackoff = defaultMaxBackoff
+	}
+
+	if opt.MaxRetries <= 0 {
+		opt.MaxRetries = defaultMaxRetries
+	}
+
+	if opt.Timeout <= 0 {
+		opt.Timeout = defaultTimeout
+	}
+
+	if opt.HTTPClient == nil {
+		opt.HTTPClient = &http.Client{
+			Timeout: opt.Timeout,
+		}
+	}
+
+	return opt
+}
+
+func endpointFromEnv() string {
+	if v := os.Getenv("LOG_OTLP_ENDPOINT"); len(v) > 0 {
+		return v
+	}
+
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"); len(v) > 0 {
+		return v
+	}
+
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); len(v) > 0 {
+		return strings.TrimSuffix(v, "/") + "/v1/logs"
+	}
+
+	return defaultEndpoint
+}
+
+type RootLogger interface {
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Printf(format string, args ...interface{})
+}
+
+// NewHook initializes a new logrus.Hook that exports entries as OTLP log records
+// over HTTP. Records are batched and exported in background, failed exports are
+// retried with backoff. The hook implements Flush and Close, called on suplog Close.
+func NewHook(logger RootLogger, opt *HookOptions) logrus.Hook {
+	opt = checkHookOptions(opt)
+
+	h := &hook{
+		opt:      opt,
+		logger:   logger,
+		resource: newResource(opt),
+		current:  newBatch(),
+	}
+
+	h.batcher = batcher.New(batcher.Options{
+		QueueSize:     opt.QueueSize,
+		FlushInterval: opt.FlushInterval,
+		Add:           h.add,
+		Export:        h.export,
+		Dropped:       h.reportDropped,
+	})
+
+	return h
+}
+
+type hook struct {
+	opt      *HookOptions
+	logger   RootLogger
+	resource *resourcepb.Resource
+
+	batcher *batcher.Batcher
+	// current is the batch of the background goroutine
+	current *batch
+}
+
+// message is a record along with its instrumentation scope.
+type message struct {
+	scope  string
+	record *logspb.LogRecord
+}
+
+func (h *hook) Levels() []logrus.Level {
+	return h.opt.Levels
+}
+
+func (h *hook) Fire(e *logrus.Entry) error {
+	if h.batcher.Closed() {
+		return nil
+	}
+
+	scope := ScopeName
+	if name, ok := e.Data[loggerField].(string); ok && len(name) > 0 {
+		scope = name
+	}
+
+	msg := message{
+		scope:  scope,
+		record: newRecord(e),
+	}
+
+	h.batcher.Enqueue(msg)
+
+	return nil
+}
+
+// Flush blocks until records queued before the call are exported, or ctx is done.
+func (h *hook) Flush(ctx context.Context) error {
+	return h.batcher.Flush(ctx)
+}
+
+// Close exports queued records and stops the hook.
+func (h *hook) Close(ctx context.Context) error {
+	return h.batcher.Close(ctx)
+}
+
+// newResource describes the service, mapping Env and AppVersion onto
+// deployment.environment and service.version attributes.
+func newResource(opt *HookOptions) *resourcepb.Resource {
+	attrs := []*commonpb.KeyValue{
+		keyValue("service.name", stringValue(opt.ServiceName)),
+		keyValue("deployment.environment", stringValue(opt.Env)),
+	}
+
+	if len(opt.AppVersion) > 0 {
+		attrs = append(attrs, keyValue("service.version", stringValue(opt.AppVersion)))
+	}
+
+	keys := make([]string, 0, len(opt.ResourceAttributes))
+	for k := range opt.ResourceAttributes {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		attrs = append(attrs, keyValue(k, stringValue(opt.ResourceAttributes[k])))
+	}
+
+	return &resourcepb.Resource{
+		Attributes: attrs,
+	}
+}
+
+// newRecord converts the entry into OTLP log record. Fields become attributes,
+// the error is mapped onto exception attributes, fn and src of the debug hook
+// onto code attributes, trace fields of the otel hook onto the trace context.
+func newRecord(e *logrus.Entry) *logspb.LogRecord {
+	record := &logspb.LogRecord{
+		TimeUnixNano:         uint64(e.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       severityNumber(e.Level),
+		SeverityText:         e.Level.String(),
+		Body:                 stringValue(strings.TrimSuffix(e.Message, "\n")),
+		Attributes:           make([]*commonpb.KeyValue, 0, len(e.Data)),
+	}
+
+	for k, v := range e.Data {
+		switch k {
+		case loggerField:
+			continue
+		case logrus.ErrorKey:
+			if err, ok := v.(error); ok {
+				record.Attributes = append(record.Attributes,
+					keyValue("exception.message", stringValue(err.Error())),
+					keyValue("exception.type", stringValue(fmt.Sprintf("%T", err))),
+				)
+
+				continue
+			}
+		case fnField:
+			k = "code.function"
+		case srcField:
+			if src, ok := v.(string); ok {
+				if idx := strings.LastIndexByte(src, ':'); idx >= 0 {
+					if line, err := strconv.ParseInt(src[idx+1:], 10, 64); err == nil {
+						record.Attributes = append(record.Attributes,
+							keyValue("code.filepath", stringValue(src[:idx])),
+							keyValue("code.lineno", intValue(line)),
+						)
+
+						continue
+					}
+				}
+			}
+
+			k = "code.filepath"
+		case traceIDField:
+			if id, ok := decodeHex(v, 16); ok {
+				record.TraceId = id
+				continue
+			}
+		case spanIDField:
+			if id, ok := decodeHex(v, 8); ok {
+				record.SpanId = id
+				continue
+			}
+		case traceFlagsField:
+			if flags, ok := decodeHex(v, 1); ok {
+				record.Flags = uint32(flags[0])
+				continue
+			}
+		}
+
+		record.Attributes = append(record.Attributes, keyValue(k, anyValue(v)))
+	}
+
+	return record
+}
+
+// severityNumber maps logrus levels onto OTLP severity numbers.
+func severityNumber(level logrus.Level) logspb.SeverityNumber {
+	switch level {
+	case logrus.PanicLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4
+	case logrus.FatalLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	case logrus.ErrorLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case logrus.WarnLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case logrus.InfoLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case logrus.DebugLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_TRACE
+	}
+}
+
+func keyValue(k string, v *commonpb.AnyValue) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   k,
+		Value: v,
+	}
+}
+
+func stringValue(v string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{
+		Value: &commonpb.AnyValue_StringValue{StringValue: v},
+	}
+}
+
+func intValue(v int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{
+		Value: &commonpb.AnyValue_IntValue{IntValue: v},
+	}
+}
+
+func anyValue(v interface{}) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return stringValue(v)
+	case bool:
+		return &commonpb.AnyValue{
+			Value: &commonpb.AnyValue_BoolValue{BoolValue: v},
+		}
+	case int:
+		return intValue(int64(v))
+	case int8:
+		return intValue(int64(v))
+	case int16:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint8:
+		return intValue(int64(v))
+	case uint16:
+		return intValue(int64(v))
+	case uint32:
+		return intValue(int64(v))
+	case uint:
+		if uint64(v) <= math.MaxInt64 {
+			return intValue(int64(v))
+		}
+	case uint64:
+		if v <= math.MaxInt64 {
+			return intValue(int64(v))
+		}
+	case float32:
+		return &commonpb.AnyValue{
+			Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(v)},
+		}
+	case float64:
+		return &commonpb.AnyValue{
+			Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v},
+		}
+	case error:
+		return stringValue(v.Error())
+	case time.Time:
+		return stringValue(v.Format(time.RFC3339Nano))
+	}
+
+	return stringValue(fmt.Sprint(v))
+}
+
+func decodeHex(v interface{}, size int) ([]byte, bool) {
+	s, ok := v.(string)
+	if !ok || len(s) != size*2 {
+		return nil, false
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+
+	return b, true
+}
@@ -0,0 +1,260 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+
+	otlpHook "github.com/xlab/suplog/hooks/otlp"
+	"github.com/xlab/suplog/suplogtest"
+
+	"github.com/xlab/suplog"
+)
+
+func attributes(kvs []*commonpb.KeyValue) map[string]*commonpb.AnyValue {
+	result := make(map[string]*commonpb.AnyValue, len(kvs))
+	for _, kv := range kvs {
+		result[kv.Key] = kv.Value
+	}
+
+	return result
+}
+
+func TestOTLPHookProtobuf(t *testing.T) {
+	c := suplogtest.NewHTTPServer(t, "/v1/logs", http.StatusOK)
+
+	hook := otlpHook.NewHook(suplog.DefaultLogger, &otlpHook.HookOptions{
+		Endpoint:    c.URL + "/v1/logs",
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		Env:         "prod",
+		AppVersion:  "1.2.3",
+		ServiceName: "api",
+	})
+
+	ts := time.Unix(1622545200, 42)
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Named("db").WithFields(suplog.Fields{
+		"error":       errors.New("timeout"),
+		"rows":        42,
+		"ratio":       0.5,
+		"cached":      true,
+		"src":         "app/main.go:12",
+		"trace_id":    "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":     "00f067aa0ba902b7",
+		"trace_flags": "01",
+	}).WithTime(ts).Error("query failed")
+	logger.Info("started")
+
+	if err := hook.(suplog.Closer).Close(context.Background()); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	requests, bodies := c.Received()
+	if len(requests) != 1 || requests[0].Header.Get("Content-Type") != "application/x-protobuf" {
+		t.Fatalf("expected a single protobuf export request, got %d", len(requests))
+	}
+
+	if requests[0].Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("expected custom headers, got %v", requests[0].Header)
+	}
+
+	var req collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(bodies[0], &req); err != nil {
+		t.Fatal(err)
+	}
+
+	resource := attributes(req.ResourceLogs[0].Resource.Attributes)
+	if resource["deployment.environment"].GetStringValue() != "prod" ||
+		resource["service.version"].GetStringValue() != "1.2.3" ||
+		resource["service.name"].GetStringValue() != "api" {
+		t.Errorf("unexpected resource: %v", resource)
+	}
+
+	scopes := req.ResourceLogs[0].ScopeLogs
+	if len(scopes) != 2 || scopes[0].Scope.Name != "db" || scopes[1].Scope.Name != otlpHook.ScopeName {
+		t.Fatalf("expected records scoped by logger names, got %v", scopes)
+	}
+
+	record := scopes[0].LogRecords[0]
+	if record.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_ERROR || record.SeverityText != "error" {
+		t.Errorf("unexpected severity: %v %s", record.SeverityNumber, record.SeverityText)
+	}
+
+	if record.Body.GetStringValue() != "query failed" || record.TimeUnixNano != uint64(ts.UnixNano()) {
+		t.Errorf("unexpected record: %v", record)
+	}
+
+	if len(record.TraceId) != 16 || len(record.SpanId) != 8 || record.Flags != 1 {
+		t.Errorf("unexpected trace context: %x %x %d", record.TraceId, record.SpanId, record.Flags)
+	}
+
+	attrs := attributes(record.Attributes)
+	if attrs["exception.message"].GetStringValue() != "timeout" ||
+		attrs["rows"].GetIntValue() != 42 ||
+		attrs["ratio"].GetDoubleValue() != 0.5 ||
+		!attrs["cached"].GetBoolValue() ||
+		attrs["code.filepath"].GetStringValue() != "app/main.go" ||
+		attrs["code.lineno"].GetIntValue() != 12 {
+		t.Errorf("unexpected attributes: %v", attrs)
+	}
+
+	for _, k := range []string{"logger", "trace_id", "span_id", "error"} {
+		if _, ok := attrs[k]; ok {
+			t.Errorf("expected %s not kept as attribute", k)
+		}
+	}
+
+	if severity := scopes[1].LogRecords[0].SeverityNumber; severity != logspb.SeverityNumber_SEVERITY_NUMBER_INFO {
+		t.Errorf("unexpected info severity: %v", severity)
+	}
+}
+
+func TestOTLPHookJSON(t *testing.T) {
+	c := suplogtest.NewHTTPServer(t, "/v1/logs", http.StatusOK)
+
+	hook := otlpHook.NewHook(suplog.DefaultLogger, &otlpHook.HookOptions{
+		Endpoint: c.URL + "/v1/logs",
+		Encoding: otlpHook.EncodingJSON,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.WithFields(suplog.Fields{
+		"rows":     42,
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+	}).Warning("slow query")
+
+	suplogtest.FlushHook(t, hook)
+
+	requests, bodies := c.Received()
+	if len(requests) != 1 || requests[0].Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a single JSON export request, got %d", len(requests))
+	}
+
+	var req struct {
+		ResourceLogs []struct {
+			ScopeLogs []struct {
+				LogRecords []map[string]interface{} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+
+	if err := json.Unmarshal(bodies[0], &req); err != nil {
+		t.Fatal(err)
+	}
+
+	record := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if record["severityNumber"] != float64(13) || record["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("unexpected record: %s", bodies[0])
+	}
+
+	if _, ok := record["timeUnixNano"].(string); !ok {
+		t.Errorf("expected timestamps encoded as strings, got %s", bodies[0])
+	}
+
+	if !strings.Contains(string(bodies[0]), `{"key":"rows","value":{"intValue":"42"}}`) {
+		t.Errorf("expected int attributes encoded as strings, got %s", bodies[0])
+	}
+}
+
+func TestOTLPHookRetry(t *testing.T) {
+	c := suplogtest.NewHTTPServer(t, "/v1/logs", http.StatusOK)
+	c.Fail(2, nil)
+
+	hook := otlpHook.NewHook(suplog.DefaultLogger, &otlpHook.HookOptions{
+		Endpoint:   c.URL + "/v1/logs",
+		Encoding:   otlpHook.EncodingJSON,
+		MinBackoff: 10 * time.Millisecond,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("retried")
+
+	suplogtest.FlushHook(t, hook)
+
+	if _, bodies := c.Received(); len(bodies) != 1 || !strings.Contains(string(bodies[0]), "retried") {
+		t.Errorf("expected the record exported after retries, got %q", bodies)
+	}
+}
+
+func TestOTLPHookRetryAfter(t *testing.T) {
+	c := suplogtest.NewHTTPServer(t, "/v1/logs", http.StatusOK)
+	c.Fail(1, http.Header{"Retry-After": []string{"1"}})
+
+	hook := otlpHook.NewHook(suplog.DefaultLogger, &otlpHook.HookOptions{
+		Endpoint:   c.URL + "/v1/logs",
+		MinBackoff: 10 * time.Millisecond,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("throttled")
+
+	start := time.Now()
+	suplogtest.FlushHook(t, hook)
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the export retried after Retry-After delay, retried after %s", elapsed)
+	}
+
+	if requests, _ := c.Received(); len(requests) != 1 {
+		t.Errorf("expected the record exported after the delay, got %d requests", len(requests))
+	}
+}
+
+func TestOTLPHookExportFailure(t *testing.T) {
+	c := suplogtest.NewHTTPServer(t, "/v1/logs", http.StatusBadRequest)
+
+	rec := suplogtest.NewRecorder(nil)
+
+	hook := otlpHook.NewHook(rec, &otlpHook.HookOptions{
+		Endpoint: c.URL + "/v1/logs",
+		Encoding: otlpHook.EncodingJSON,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("rejected")
+
+	suplogtest.FlushHook(t, hook)
+
+	rec.AssertLogged(t, suplog.ErrorLevel, "failed to export 1 records to OTLP", nil)
+}
+
+func TestOTLPHookPartialSuccess(t *testing.T) {
+	c := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"partialSuccess":{"rejectedLogRecords":"1","errorMessage":"record too large"}}`)
+	}))
+	defer c.Close()
+
+	rec := suplogtest.NewRecorder(nil)
+
+	hook := otlpHook.NewHook(rec, &otlpHook.HookOptions{
+		Endpoint: c.URL + "/v1/logs",
+		Encoding: otlpHook.EncodingJSON,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("partial")
+
+	suplogtest.FlushHook(t, hook)
+
+	rec.AssertLogged(t, suplog.ErrorLevel, "failed to export 1 records to OTLP, rejected: record too large", nil)
+}
@@ -0,0 +1,50 @@
+package stack
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TextFormatter renders the stack attached by the hook as indented lines
+// following the entry line, other fields are formatted by the wrapped Formatter.
+type TextFormatter struct {
+	logrus.Formatter
+}
+
+// NewTextFormatter wraps the formatter, provide nil to use logrus.TextFormatter.
+func NewTextFormatter(formatter logrus.Formatter) *TextFormatter {
+	if formatter == nil {
+		formatter = new(logrus.TextFormatter)
+	}
+
+	return &TextFormatter{
+		Formatter: formatter,
+	}
+}
+
+func (f *TextFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	frames, ok := e.Data[Field].([]Frame)
+	if !ok {
+		return f.Formatter.Format(e)
+	}
+
+	withoutStack := e.Dup()
+	withoutStack.Level = e.Level
+	withoutStack.Message = e.Message
+	withoutStack.Caller = e.Caller
+	delete(withoutStack.Data, Field)
+
+	serialized, err := f.Formatter.Format(withoutStack)
+	if err != nil {
+		return nil, err
+	}
+
+	b := bytes.NewBuffer(serialized)
+	for _, frame := range frames {
+		fmt.Fprintf(b, "\t%s\n\t\t%s:%d\n", frame.Func, frame.File, frame.Line)
+	}
+
+	return b.Bytes(), nil
+}
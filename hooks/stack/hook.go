@@ -0,0 +1,205 @@
+package stack
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/xlab/suplog/stackcache"
+)
+
+// Field is the name of the field that carries the rendered stack.
+const Field = "stack"
+
+// Frame is a rendered stack frame, marshalled as {"func","file","line"} by JSON formatter.
+type Frame struct {
+	Func string `json:"func"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+type forcedKey struct{}
+
+// ContextWithForced returns a copy of ctx that makes the hook attach the stack
+// to entries logged with it regardless of their level, see suplog.WithStack.
+func ContextWithForced(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, forcedKey{}, true)
+}
+
+// isForced reports whether the entry context forces the stack.
+func isForced(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+
+	forced, _ := ctx.Value(forcedKey{}).(bool)
+	return forced
+}
+
+// HookOptions allows to set additional Hook options.
+type HookOptions struct {
+	// Levels enables this hook for all listed levels, Error, Fatal and Panic by default.
+	Levels []logrus.Level
+	// PathSegmentsLimit allows to trim amount of source code file path segments.
+	PathSegmentsLimit int
+	// MaxFrames limits the amount of rendered frames, 0 renders all.
+	MaxFrames int
+	// StackTraceOffset allows to wrap logger into greater stack depth and still
+	// get reports on accurate positions.
+	StackTraceOffset int
+}
+
+func checkHookOptions(opt *HookOptions) *HookOptions {
+	if opt == nil {
+		opt = &HookOptions{}
+	}
+
+	if len(opt.Levels) == 0 {
+		opt.Levels = []logrus.Level{
+			logrus.PanicLevel,
+			logrus.FatalLevel,
+			logrus.ErrorLevel,
+		}
+	}
+
+	if opt.PathSegmentsLimit == 0 {
+		opt.PathSegmentsLimit = 3
+	}
+
+	return opt
+}
+
+type RootLogger interface {
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Printf(format string, args ...interface{})
+}
+
+const defaultStackSearchOffset = 6
+
+// NewHook initializes a new logrus.Hook that attaches the rendered stack to entries,
+// using the stack of the error in fields when it has one (see pkg/errors),
+// or the stack of the logging call otherwise.
+func NewHook(logger RootLogger, opt *HookOptions) logrus.Hook {
+	opt = checkHookOptions(opt)
+
+	levels := make(map[logrus.Level]bool, len(opt.Levels))
+	for _, level := range opt.Levels {
+		levels[level] = true
+	}
+
+	return &hook{
+		opt:    opt,
+		logger: logger,
+		levels: levels,
+		stack:  stackcache.New(defaultStackSearchOffset, opt.StackTraceOffset, "github.com/xlab/suplog"),
+	}
+}
+
+type hook struct {
+	opt    *HookOptions
+	logger RootLogger
+	levels map[logrus.Level]bool
+	stack  stackcache.StackCache
+}
+
+// Levels returns all levels, as forced entries are handled on any level.
+func (h *hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *hook) Fire(e *logrus.Entry) error {
+	if !h.levels[e.Level] && !isForced(e.Context) {
+		return nil
+	}
+
+	frames, ok := errorFrames(e.Data[logrus.ErrorKey])
+	if !ok {
+		if frames, ok = stackcache.FramesFromContext(e.Context); !ok {
+			frames = h.stack.GetStackFrames()
+		}
+	}
+
+	if h.opt.MaxFrames > 0 && len(frames) > h.opt.MaxFrames {
+		frames = frames[:h.opt.MaxFrames]
+	}
+
+	rendered := make([]Frame, 0, len(frames))
+	for _, f := range frames {
+		rendered = append(rendered, Frame{
+			Func: f.Function,
+			File: limitPath(f.File, h.opt.PathSegmentsLimit),
+			Line: f.Line,
+		})
+	}
+
+	e.Data[Field] = rendered
+
+	return nil
+}
+
+type pkgErrorsStackTracer interface {
+	StackTrace() pkgerrors.StackTrace
+}
+
+// errorFrames returns the stack of the innermost error in chain that has one.
+func errorFrames(v interface{}) ([]runtime.Frame, bool) {
+	err, ok := v.(error)
+	if !ok {
+		return nil, false
+	}
+
+	var stackTrace pkgerrors.StackTrace
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		if tracer, ok := err.(pkgErrorsStackTracer); ok {
+			stackTrace = tracer.StackTrace()
+		}
+	}
+
+	if len(stackTrace) == 0 {
+		return nil, false
+	}
+
+	pcs := make([]uintptr, len(stackTrace))
+	for i, f := range stackTrace {
+		pcs[i] = uintptr(f)
+	}
+
+	var frames []runtime.Frame
+
+	callersFrames := runtime.CallersFrames(pcs)
+	for {
+		f, more := callersFrames.Next()
+		frames = append(frames, f)
+
+		if !more {
+			break
+		}
+	}
+
+	return frames, true
+}
+
+func limitPath(path string, n int) string {
+	if n <= 0 {
+		return path
+	}
+
+	pathParts := strings.Split(path, string(filepath.Separator))
+	if len(pathParts) > n {
+		pathParts = pathParts[len(pathParts)-n:]
+	}
+
+	return filepath.Join(pathParts...)
+}
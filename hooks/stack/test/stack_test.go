@@ -0,0 +1,91 @@
+package stack
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	stackHook "github.com/xlab/suplog/hooks/stack"
+
+	"github.com/xlab/suplog"
+)
+
+type jsonEntry struct {
+	Msg   string            `json:"msg"`
+	Stack []stackHook.Frame `json:"stack"`
+}
+
+func parseEntries(t *testing.T, out *bytes.Buffer) []jsonEntry {
+	var entries []jsonEntry
+
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var e jsonEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("failed to parse entry %s: %v", line, err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+func newFailure() error {
+	return errors.New("failure")
+}
+
+func TestStackHook(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := suplog.NewLogger(out, new(suplog.JSONFormatter), stackHook.NewHook(suplog.DefaultLogger, nil))
+
+	logger.Error("with call stack")
+	logger.WithError(errors.Wrap(newFailure(), "wrapped")).Error("with error stack")
+	logger.Info("without stack")
+	logger.WithStack().Info("with forced stack")
+
+	entries := parseEntries(t, out)
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got: %s", out.String())
+	}
+
+	if len(entries[0].Stack) == 0 || !strings.HasSuffix(entries[0].Stack[0].Func, ".TestStackHook") {
+		t.Errorf("expected stack starting at the call site, got %+v", entries[0].Stack)
+	}
+
+	if len(entries[1].Stack) == 0 || !strings.HasSuffix(entries[1].Stack[0].Func, ".newFailure") {
+		t.Errorf("expected stack of the innermost error, got %+v", entries[1].Stack)
+	}
+
+	if entries[2].Stack != nil {
+		t.Errorf("expected no stack on info level, got %+v", entries[2].Stack)
+	}
+
+	if len(entries[3].Stack) == 0 || entries[3].Stack[0].Line == 0 {
+		t.Errorf("expected forced stack, got %+v", entries[3].Stack)
+	}
+}
+
+func TestTextFormatter(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := suplog.NewLogger(out, stackHook.NewTextFormatter(&suplog.TextFormatter{
+		DisableTimestamp: true,
+	}), stackHook.NewHook(suplog.DefaultLogger, nil))
+
+	logger.Error("with call stack")
+
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) < 3 || strings.Contains(lines[0], "stack=") {
+		t.Fatalf("expected entry line followed by stack lines, got: %s", out.String())
+	}
+
+	if !strings.HasPrefix(lines[1], "\t") || !strings.HasSuffix(lines[1], ".TestTextFormatter") {
+		t.Errorf("expected indented function name, got %q", lines[1])
+	}
+
+	if !strings.HasPrefix(lines[2], "\t\t") || !strings.Contains(lines[2], "stack_test.go:") {
+		t.Errorf("expected indented source line, got %q", lines[2])
+	}
+}
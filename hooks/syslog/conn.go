@@ -0,0 +1,192 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// localAddrs are paths of the local syslog daemon socket.
+var localAddrs = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+const minReconnectDelay = 100 * time.Millisecond
+
+var errPeerClosed = errors.New("connection closed by peer")
+
+// conn is a connection to the syslog server.
+type conn struct {
+	net.Conn
+
+	// framed enables octet-counting framing of stream connections
+	framed bool
+	// closed is set once the peer closes the stream connection
+	closed int32
+}
+
+func newConn(c net.Conn, framed bool) *conn {
+	cn := &conn{
+		Conn:   c,
+		framed: framed,
+	}
+
+	if framed {
+		// syslog servers never write, so reads return only when the connection is closed
+		go cn.watch()
+	}
+
+	return cn
+}
+
+func (c *conn) watch() {
+	_, _ = io.Copy(io.Discard, c.Conn)
+	atomic.StoreInt32(&c.closed, 1)
+}
+
+func (c *conn) write(data []byte, timeout time.Duration) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return errPeerClosed
+	}
+
+	if c.framed {
+		frame := make([]byte, 0, len(data)+8)
+		frame = strconv.AppendInt(frame, int64(len(data)), 10)
+		frame = append(frame, ' ')
+		data = append(frame, data...)
+	}
+
+	if err := c.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	_, err := c.Write(data)
+
+	return err
+}
+
+// dial connects to the configured server, or to the local syslog daemon.
+func (h *hook) dial() (*conn, error) {
+	switch h.opt.Network {
+	case "":
+		var err error
+		for _, addr := range localAddrs {
+			var c net.Conn
+			if c, err = net.DialTimeout("unixgram", addr, h.opt.DialTimeout); err == nil {
+				return newConn(c, false), nil
+			}
+		}
+
+		return nil, err
+	case NetworkTLS:
+		dialer := &net.Dialer{
+			Timeout: h.opt.DialTimeout,
+		}
+
+		c, err := tls.DialWithDialer(dialer, "tcp", h.opt.Addr, h.opt.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		return newConn(c, true), nil
+	case NetworkTCP, "tcp4", "tcp6":
+		c, err := net.DialTimeout(h.opt.Network, h.opt.Addr, h.opt.DialTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		return newConn(c, true), nil
+	default:
+		c, err := net.DialTimeout(h.opt.Network, h.opt.Addr, h.opt.DialTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		return newConn(c, false), nil
+	}
+}
+
+// connect dials until connected, doubling the delay between attempts.
+// Returns nil if the hook is closed meanwhile.
+func (h *hook) connect() *conn {
+	delay := minReconnectDelay
+	reported := false
+
+	for {
+		c, err := h.dial()
+		if err == nil {
+			return c
+		}
+
+		if !reported {
+			h.logger.Errorf("failed to connect to syslog: %v", err)
+			reported = true
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-h.done:
+			return nil
+		}
+
+		if delay *= 2; delay > h.opt.ReconnectDelay {
+			delay = h.opt.ReconnectDelay
+		}
+	}
+}
+
+// maxWriteAttempts limits writes of a single message, reconnecting in between.
+const maxWriteAttempts = 2
+
+// run writes queued messages until the hook is closed.
+func (h *hook) run() {
+	defer h.wg.Done()
+
+	var c *conn
+
+	defer func() {
+		if c != nil {
+			c.Close()
+		}
+	}()
+
+	for {
+		var msg message
+
+		select {
+		case msg = <-h.queue:
+		case <-h.done:
+			return
+		}
+
+		if msg.flushed != nil {
+			close(msg.flushed)
+			continue
+		}
+
+		for attempt := 1; ; attempt++ {
+			if c == nil {
+				if c = h.connect(); c == nil {
+					return
+				}
+			}
+
+			err := c.write(msg.data, h.opt.WriteTimeout)
+			if err == nil {
+				break
+			}
+
+			c.Close()
+			c = nil
+
+			if attempt == maxWriteAttempts {
+				h.logger.Errorf("failed to write to syslog: %v", err)
+				break
+			}
+		}
+
+		h.reportDropped()
+	}
+}
@@ -0,0 +1,197 @@
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// loggerField is the field that carries the name of suplog named loggers,
+// sent as MSGID of RFC 5424 messages.
+const loggerField = "logger"
+
+const rfc5424TimestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// Length limits of RFC 5424 header fields and structured data names.
+const (
+	maxHostnameLen = 255
+	maxAppNameLen  = 48
+	maxMsgIDLen    = 32
+	maxSDNameLen   = 32
+)
+
+// severity maps logrus levels onto syslog severities.
+func severity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel:
+		return 1 // alert
+	case logrus.FatalLevel:
+		return 2 // critical
+	case logrus.ErrorLevel:
+		return 3 // error
+	case logrus.WarnLevel:
+		return 4 // warning
+	case logrus.InfoLevel:
+		return 6 // informational
+	default:
+		return 7 // debug
+	}
+}
+
+func (h *hook) priority(level logrus.Level) int {
+	return int(h.opt.Facility)*8 + severity(level)
+}
+
+// formatRFC5424 formats the entry as RFC 5424 message, fields are sent as structured data.
+func (h *hook) formatRFC5424(e *logrus.Entry) []byte {
+	b := new(bytes.Buffer)
+
+	msgID := "-"
+	if name, ok := e.Data[loggerField].(string); ok {
+		msgID = headerValue(name, maxMsgIDLen)
+	}
+
+	fmt.Fprintf(b, "<%d>1 %s %s %s %d %s ",
+		h.priority(e.Level),
+		e.Time.Format(rfc5424TimestampFormat),
+		headerValue(h.opt.Hostname, maxHostnameLen),
+		headerValue(h.opt.AppName, maxAppNameLen),
+		h.pid,
+		msgID,
+	)
+
+	keys := sortedKeys(e.Data)
+	if len(keys) == 0 || (len(keys) == 1 && msgID != "-") {
+		b.WriteByte('-')
+	} else {
+		b.WriteByte('[')
+		b.WriteString(sdName(h.opt.SDID))
+
+		for _, k := range keys {
+			if k == loggerField && msgID != "-" {
+				continue
+			}
+
+			b.WriteByte(' ')
+			b.WriteString(sdName(k))
+			b.WriteString(`="`)
+			writeSDValue(b, fieldValue(e.Data[k]))
+			b.WriteByte('"')
+		}
+
+		b.WriteByte(']')
+	}
+
+	if msg := strings.TrimSuffix(e.Message, "\n"); len(msg) > 0 {
+		b.WriteByte(' ')
+		b.WriteString(msg)
+	}
+
+	return b.Bytes()
+}
+
+// formatRFC3164 formats the entry as BSD syslog message, fields are appended to the message.
+func (h *hook) formatRFC3164(e *logrus.Entry) []byte {
+	b := new(bytes.Buffer)
+
+	fmt.Fprintf(b, "<%d>%s %s %s[%d]: %s",
+		h.priority(e.Level),
+		e.Time.Format(time.Stamp),
+		headerValue(h.opt.Hostname, maxHostnameLen),
+		headerValue(h.opt.AppName, maxAppNameLen),
+		h.pid,
+		strings.TrimSuffix(e.Message, "\n"),
+	)
+
+	for _, k := range sortedKeys(e.Data) {
+		value := fieldValue(e.Data[k])
+		if len(value) == 0 || strings.ContainsAny(value, " \"=\n") {
+			value = strconv.Quote(value)
+		}
+
+		b.WriteByte(' ')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(value)
+	}
+
+	return b.Bytes()
+}
+
+func sortedKeys(data logrus.Fields) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func fieldValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// headerValue returns the value limited to printable ASCII without spaces, "-" if empty.
+func headerValue(s string, maxLen int) string {
+	s = printableASCII(s, "")
+	if len(s) == 0 {
+		return "-"
+	}
+
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+
+	return s
+}
+
+// sdName returns the valid SD-ID or PARAM-NAME, replacing other characters with '_'.
+func sdName(s string) string {
+	s = printableASCII(s, `="]`)
+	if len(s) == 0 {
+		return "_"
+	}
+
+	if len(s) > maxSDNameLen {
+		s = s[:maxSDNameLen]
+	}
+
+	return s
+}
+
+func printableASCII(s, disallowed string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune(disallowed, r) {
+			return '_'
+		}
+
+		return r
+	}, s)
+}
+
+// writeSDValue writes PARAM-VALUE, escaping '"', '\' and ']'.
+func writeSDValue(b *bytes.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\', ']':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/xlab/suplog/hooks/syslog
+
+go 1.16
+
+require (
+	github.com/sirupsen/logrus v1.9.0
+	github.com/xlab/suplog v1.4.2-0.20261017051833-a3d4ac419e46
+)
+
+replace github.com/xlab/suplog => ../../
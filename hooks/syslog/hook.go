@@ -0,0 +1,272 @@
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Format of syslog messages.
+type Format int
+
+const (
+	// RFC5424 formats messages as defined by RFC 5424, fields are sent as structured data.
+	RFC5424 Format = iota
+	// RFC3164 formats messages in the legacy BSD format, fields are appended to the message.
+	RFC3164
+)
+
+// Facility of syslog messages, the kernel facility is not available to processes.
+type Facility int
+
+const (
+	FacilityUser Facility = iota + 1
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+)
+
+const (
+	FacilityLocal0 Facility = iota + 16
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+// Supported networks, along with "udp" and "unixgram".
+const (
+	// NetworkTCP sends messages over TCP with octet-counting framing (RFC 6587).
+	NetworkTCP = "tcp"
+	// NetworkTLS sends messages over TLS with octet-counting framing (RFC 5425).
+	NetworkTLS = "tls"
+)
+
+// DefaultSDID is the structured data ID used for fields in RFC 5424 messages.
+const DefaultSDID = "fields@32473"
+
+const (
+	defaultBufferSize     = 1000
+	defaultDialTimeout    = 5 * time.Second
+	defaultWriteTimeout   = 5 * time.Second
+	defaultReconnectDelay = 5 * time.Second
+)
+
+// HookOptions allows to set additional Hook options.
+type HookOptions struct {
+	// Levels enables this hook for all listed levels, all levels by default.
+	Levels []logrus.Level
+	// Network is either "unixgram", "udp", "tcp" or "tls" (LOG_SYSLOG_NETWORK),
+	// udp by default when Addr is set. The local syslog daemon is used when
+	// both Network and Addr are empty.
+	Network string
+	// Addr is the address of the syslog server (LOG_SYSLOG_ADDR), e.g. "logs.local:514".
+	Addr string
+	// TLSConfig is used by the tls network.
+	TLSConfig *tls.Config
+	// Format of messages, RFC5424 by default.
+	Format Format
+	// Facility of messages, FacilityUser by default.
+	Facility Facility
+	// Hostname sets HOSTNAME of messages, os.Hostname by default.
+	Hostname string
+	// AppName sets APP-NAME of messages, the executable name by default.
+	AppName string
+	// SDID sets the structured data ID used for fields, DefaultSDID by default.
+	SDID string
+	// BufferSize limits messages buffered while the server is unavailable,
+	// newer messages are dropped when the buffer is full. 1000 by default.
+	BufferSize int
+	// DialTimeout bounds connecting to the server, 5s by default.
+	DialTimeout time.Duration
+	// WriteTimeout bounds writing a message, 5s by default.
+	WriteTimeout time.Duration
+	// ReconnectDelay is the maximum delay between reconnection attempts,
+	// the delay is doubled after each failed attempt. 5s by default.
+	ReconnectDelay time.Duration
+}
+
+func checkHookOptions(opt *HookOptions) *HookOptions {
+	if opt == nil {
+		opt = &HookOptions{}
+	}
+
+	if len(opt.Levels) == 0 {
+		opt.Levels = logrus.AllLevels
+	}
+
+	if len(opt.Network) == 0 {
+		opt.Network = os.Getenv("LOG_SYSLOG_NETWORK")
+	}
+
+	if len(opt.Addr) == 0 {
+		opt.Addr = os.Getenv("LOG_SYSLOG_ADDR")
+	}
+
+	if len(opt.Network) == 0 && len(opt.Addr) > 0 {
+		opt.Network = "udp"
+	}
+
+	if opt.Facility <= 0 {
+		opt.Facility = FacilityUser
+	}
+
+	if len(opt.Hostname) == 0 {
+		if hostname, err := os.Hostname(); err == nil {
+			opt.Hostname = hostname
+		} else {
+			opt.Hostname = "-"
+		}
+	}
+
+	if len(opt.AppName) == 0 {
+		opt.AppName = filepath.Base(os.Args[0])
+	}
+
+	if len(opt.SDID) == 0 {
+		opt.SDID = DefaultSDID
+	}
+
+	if opt.BufferSize <= 0 {
+		opt.BufferSize = defaultBufferSize
+	}
+
+	if opt.DialTimeout <= 0 {
+		opt.DialTimeout = defaultDialTimeout
+	}
+
+	if opt.WriteTimeout <= 0 {
+		opt.WriteTimeout = defaultWriteTimeout
+	}
+
+	if opt.ReconnectDelay <= 0 {
+		opt.ReconnectDelay = defaultReconnectDelay
+	}
+
+	return opt
+}
+
+type RootLogger interface {
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Printf(format string, args ...interface{})
+}
+
+// NewHook initializes a new logrus.Hook that ships entries to a syslog server.
+// Messages are written in background, buffered and retried while the server is
+// unavailable. The hook implements Flush and Close, called on suplog Close.
+func NewHook(logger RootLogger, opt *HookOptions) logrus.Hook {
+	opt = checkHookOptions(opt)
+
+	h := &hook{
+		opt:    opt,
+		logger: logger,
+		pid:    os.Getpid(),
+		queue:  make(chan message, opt.BufferSize),
+		done:   make(chan struct{}),
+	}
+
+	h.wg.Add(1)
+	go h.run()
+
+	return h
+}
+
+type hook struct {
+	opt    *HookOptions
+	logger RootLogger
+	pid    int
+
+	queue   chan message
+	done    chan struct{}
+	wg      sync.WaitGroup
+	closed  int32
+	dropped uint64
+
+	closeOnce sync.Once
+}
+
+// message is either a formatted entry, or a flush marker.
+type message struct {
+	data    []byte
+	flushed chan struct{}
+}
+
+func (h *hook) Levels() []logrus.Level {
+	return h.opt.Levels
+}
+
+func (h *hook) Fire(e *logrus.Entry) error {
+	if atomic.LoadInt32(&h.closed) == 1 {
+		return nil
+	}
+
+	var data []byte
+	if h.opt.Format == RFC3164 {
+		data = h.formatRFC3164(e)
+	} else {
+		data = h.formatRFC5424(e)
+	}
+
+	select {
+	case h.queue <- message{data: data}:
+	default:
+		atomic.AddUint64(&h.dropped, 1)
+	}
+
+	return nil
+}
+
+// Flush blocks until messages queued before the call are written, or ctx is done.
+func (h *hook) Flush(ctx context.Context) error {
+	if atomic.LoadInt32(&h.closed) == 1 {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+
+	select {
+	case h.queue <- message{flushed: flushed}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes queued messages and closes the connection.
+func (h *hook) Close(ctx context.Context) error {
+	err := h.Flush(ctx)
+
+	h.closeOnce.Do(func() {
+		atomic.StoreInt32(&h.closed, 1)
+		close(h.done)
+	})
+
+	h.wg.Wait()
+
+	return err
+}
+
+// reportDropped reports messages dropped due to the full buffer, if any.
+func (h *hook) reportDropped() {
+	if n := atomic.SwapUint64(&h.dropped, 0); n > 0 {
+		h.logger.Errorf("failed to send %d messages to syslog, buffer is full", n)
+	}
+}
@@ -0,0 +1,249 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	syslogHook "github.com/xlab/suplog/hooks/syslog"
+
+	"github.com/xlab/suplog"
+)
+
+// tcpServer accepts connections and sends octet-counted frames into the channel.
+type tcpServer struct {
+	ln     net.Listener
+	frames chan string
+	conns  chan net.Conn
+}
+
+func newTCPServer(t *testing.T) *tcpServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &tcpServer{
+		ln:     ln,
+		frames: make(chan string, 100),
+		conns:  make(chan net.Conn, 10),
+	}
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			s.conns <- c
+			go s.read(c)
+		}
+	}()
+
+	t.Cleanup(func() {
+		ln.Close()
+	})
+
+	return s
+}
+
+func (s *tcpServer) read(c net.Conn) {
+	r := bufio.NewReader(c)
+
+	for {
+		lenStr, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(lenStr))
+		if err != nil {
+			return
+		}
+
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return
+		}
+
+		s.frames <- string(frame)
+	}
+}
+
+func (s *tcpServer) next(t *testing.T) string {
+	select {
+	case frame := <-s.frames:
+		return frame
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for a message")
+		return ""
+	}
+}
+
+func TestSyslogHookTCP(t *testing.T) {
+	server := newTCPServer(t)
+
+	hook := syslogHook.NewHook(suplog.DefaultLogger, &syslogHook.HookOptions{
+		Network:  syslogHook.NetworkTCP,
+		Addr:     server.ln.Addr().String(),
+		Facility: syslogHook.FacilityLocal0,
+		Hostname: "node-1",
+		AppName:  "api",
+	})
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Named("db").WithFields(suplog.Fields{
+		"user":  "max",
+		"query": `select "]"`,
+		"error": errors.New("timeout"),
+	}).Warning("query failed\nretrying")
+	logger.Info("no fields")
+
+	exp := regexp.MustCompile(`^<132>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}\S+ node-1 api \d+ db ` +
+		regexp.QuoteMeta(`[fields@32473 error="timeout" query="select \"\]\"" user="max"] query failed`+"\nretrying") + `$`)
+
+	if frame := server.next(t); !exp.MatchString(frame) {
+		t.Errorf("unexpected message: %q", frame)
+	}
+
+	if frame := server.next(t); !regexp.MustCompile(`^<134>1 \S+ node-1 api \d+ - - no fields$`).MatchString(frame) {
+		t.Errorf("unexpected message: %q", frame)
+	}
+
+	if err := hook.(suplog.Closer).Close(context.Background()); err != nil {
+		t.Errorf("failed to close hook: %v", err)
+	}
+}
+
+func TestSyslogHookReconnect(t *testing.T) {
+	server := newTCPServer(t)
+
+	hook := syslogHook.NewHook(suplog.DefaultLogger, &syslogHook.HookOptions{
+		Network:        syslogHook.NetworkTCP,
+		Addr:           server.ln.Addr().String(),
+		ReconnectDelay: 100 * time.Millisecond,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("before")
+
+	if frame := server.next(t); !strings.HasSuffix(frame, " before") {
+		t.Fatalf("unexpected message: %q", frame)
+	}
+
+	(<-server.conns).Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		logger.Info("after")
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := hook.(suplog.Flusher).Flush(ctx)
+		cancel()
+
+		if err != nil {
+			t.Fatalf("failed to flush: %v", err)
+		}
+
+		select {
+		case frame := <-server.frames:
+			if !strings.HasSuffix(frame, " after") {
+				t.Fatalf("unexpected message: %q", frame)
+			}
+
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	t.Fatal("expected message after reconnect")
+}
+
+func TestSyslogHookUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	hook := syslogHook.NewHook(suplog.DefaultLogger, &syslogHook.HookOptions{
+		Addr:     pc.LocalAddr().String(),
+		Format:   syslogHook.RFC3164,
+		Hostname: "node-1",
+		AppName:  "api",
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.WithField("user", "max").WithField("query", "select 1").Error("query failed")
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := regexp.MustCompile(`^<11>\w{3} [ \d]\d \d\d:\d\d:\d\d node-1 api\[\d+\]: query failed query="select 1" user=max$`)
+	if msg := string(buf[:n]); !exp.MatchString(msg) {
+		t.Errorf("unexpected message: %q", msg)
+	}
+}
+
+func TestSyslogHookBuffering(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+	ln.Close()
+
+	hook := syslogHook.NewHook(suplog.DefaultLogger, &syslogHook.HookOptions{
+		Network:        syslogHook.NetworkTCP,
+		Addr:           addr,
+		ReconnectDelay: 50 * time.Millisecond,
+	})
+	defer hook.(suplog.Closer).Close(context.Background())
+
+	logger := suplog.NewLogger(io.Discard, nil, hook)
+	logger.Info("first")
+	logger.Info("second")
+
+	time.Sleep(200 * time.Millisecond)
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("failed to listen on %s again: %v", addr, err)
+	}
+
+	server := &tcpServer{
+		ln:     ln,
+		frames: make(chan string, 100),
+		conns:  make(chan net.Conn, 10),
+	}
+	defer ln.Close()
+
+	go func() {
+		c, err := ln.Accept()
+		if err == nil {
+			server.read(c)
+		}
+	}()
+
+	for _, exp := range []string{"first", "second"} {
+		if frame := server.next(t); !strings.HasSuffix(frame, " "+exp) {
+			t.Errorf("expected %s, got %q", exp, frame)
+		}
+	}
+}
@@ -24,6 +24,15 @@ type Logger interface {
 	WithError(err error) Logger
 	WithContext(ctx context.Context) Logger
 	WithTime(t time.Time) Logger
+	WithStack() Logger
+
+	// Typed fields
+
+	With(fields ...Field) Logger
+
+	// Named loggers
+
+	Named(name string) Logger
 
 	// Logrus formatted logging methods
 
@@ -54,6 +63,11 @@ type Logger interface {
 	Errorln(args ...interface{})
 	Fatalln(args ...interface{})
 	Panicln(args ...interface{})
+
+	// Output stats
+
+	Dropped() uint64
+	LoggedAfterClose() uint64
 }
 
 type LoggerConfigurator interface {
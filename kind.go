@@ -0,0 +1,12 @@
+package suplog
+
+// KindField is the field name that tells apart Success and Notification
+// entries from plain Info entries, so formatters can render them differently
+// and hooks can filter on them.
+const KindField = "kind"
+
+// Kinds of Info entries.
+const (
+	KindSuccess      = "success"
+	KindNotification = "notification"
+)
@@ -0,0 +1,54 @@
+package suplog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestKind(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, new(JSONFormatter))
+
+	logger.Success("deployed")
+	logger.(*suplogger).Notification("new version available")
+	logger.Info("plain")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got: %s", out.String())
+	}
+
+	if !strings.Contains(lines[0], `"kind":"success"`) || !strings.Contains(lines[0], `"level":"info"`) {
+		t.Errorf("expected success kind, got %s", lines[0])
+	}
+
+	if !strings.Contains(lines[1], `"kind":"notification"`) {
+		t.Errorf("expected notification kind, got %s", lines[1])
+	}
+
+	if strings.Contains(lines[2], KindField) {
+		t.Errorf("expected no kind for plain info, got %s", lines[2])
+	}
+}
+
+func TestKindCLIMode(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, &PrettyFormatter{
+		CLIMode: true,
+	})
+
+	logger.Success("deployed")
+	logger.(*suplogger).Notification("new version available")
+	logger.Info("plain")
+	logger.Warning("careful")
+
+	exp := "✔ deployed\n" +
+		"• new version available\n" +
+		"plain\n" +
+		"WARN careful\n"
+
+	if out.String() != exp {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", out.String(), exp)
+	}
+}
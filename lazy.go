@@ -0,0 +1,62 @@
+package suplog
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// LazyValue is a field value evaluated only when the entry is actually logged,
+// before hooks and formatters see it. Construct it with Lazy.
+type LazyValue struct {
+	fn func() interface{}
+}
+
+// Lazy wraps an expensive field value, e.g. a JSON dump of a struct,
+// so it is not built when the level is disabled or the entry is sampled out:
+//
+//	log.WithField("payload", suplog.Lazy(func() interface{} {
+//		return dump(req)
+//	})).Debug("request")
+func Lazy(fn func() interface{}) LazyValue {
+	return LazyValue{
+		fn: fn,
+	}
+}
+
+// Value evaluates the lazy value.
+func (v LazyValue) Value() interface{} {
+	if v.fn == nil {
+		return nil
+	}
+
+	return v.fn()
+}
+
+// resolveLazy returns the entry with lazy values evaluated,
+// the entry is copied only if it has any.
+func resolveLazy(entry *logrus.Entry) *logrus.Entry {
+	var data Fields
+
+	for k, v := range entry.Data {
+		lazy, ok := v.(LazyValue)
+		if !ok {
+			continue
+		}
+
+		if data == nil {
+			data = copyFields(entry.Data)
+		}
+
+		data[k] = lazy.Value()
+	}
+
+	if data == nil {
+		return entry
+	}
+
+	return &logrus.Entry{
+		Logger:  entry.Logger,
+		Data:    data,
+		Time:    entry.Time,
+		Context: entry.Context,
+	}
+}
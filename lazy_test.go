@@ -0,0 +1,57 @@
+package suplog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type dataHook struct {
+	data []Fields
+}
+
+func (h *dataHook) Levels() []Level {
+	return logrus.AllLevels
+}
+
+func (h *dataHook) Fire(e *Entry) error {
+	h.data = append(h.data, copyFields(e.Data))
+	return nil
+}
+
+func TestLazy(t *testing.T) {
+	var calls int
+
+	payload := Lazy(func() interface{} {
+		calls++
+		return "expensive"
+	})
+
+	hook := new(dataHook)
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, new(JSONFormatter), hook)
+	logger.(LoggerConfigurator).SetLevel(InfoLevel)
+
+	logger.WithField("payload", payload).Debug("disabled")
+
+	if calls != 0 {
+		t.Errorf("expected lazy value not to be evaluated for disabled level, got %d calls", calls)
+	}
+
+	logger.WithFields(Fields{"payload": payload}).Info("enabled")
+	logger.With(Any("payload", payload)).Info("enabled")
+
+	if calls != 2 {
+		t.Errorf("expected lazy value to be evaluated once per entry, got %d calls", calls)
+	}
+
+	if len(hook.data) != 2 || hook.data[0]["payload"] != "expensive" || hook.data[1]["payload"] != "expensive" {
+		t.Errorf("expected hook to see evaluated value, got %v", hook.data)
+	}
+
+	if strings.Count(out.String(), `"payload":"expensive"`) != 2 {
+		t.Errorf("expected evaluated value in output, got %s", out.String())
+	}
+}
@@ -0,0 +1,227 @@
+package suplog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LevelHandler returns an http.Handler that allows to inspect and change
+// logger levels at runtime. Provide nil to control DefaultLogger.
+//
+// GET returns the effective level of the logger, or the logger specified by
+// the "logger" query param, along with levels of all registered loggers:
+//
+//	curl localhost:8080/log/level?logger=db
+//
+// PUT changes the level, an optional TTL reverts the change after the given duration:
+//
+//	curl -X PUT localhost:8080/log/level?logger=db -d '{"level":"debug","ttl":"10m"}'
+func LevelHandler(logger Logger) http.Handler {
+	if logger == nil {
+		logger = DefaultLogger
+	}
+
+	return &levelHandler{
+		logger:  logger,
+		reverts: make(map[string]*levelRevert),
+	}
+}
+
+type levelHandler struct {
+	logger Logger
+
+	mux     sync.Mutex
+	reverts map[string]*levelRevert
+}
+
+// levelRevert holds the state to restore when a temporary level expires.
+type levelRevert struct {
+	timer    *time.Timer
+	at       time.Time
+	level    Level
+	explicit bool
+}
+
+type levelRequest struct {
+	Level string `json:"level"`
+	TTL   string `json:"ttl,omitempty"`
+}
+
+type levelResponse struct {
+	Logger   string            `json:"logger,omitempty"`
+	Level    string            `json:"level"`
+	RevertAt *time.Time        `json:"revert_at,omitempty"`
+	Loggers  map[string]string `json:"loggers,omitempty"`
+}
+
+type levelErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("logger")
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getLevel(w, name)
+	case http.MethodPut:
+		h.putLevel(w, r, name)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		h.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+func (h *levelHandler) getLevel(w http.ResponseWriter, name string) {
+	registry, name, err := h.resolve(name)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	resp := levelResponse{
+		Logger:   name,
+		Level:    registry.effective(name).String(),
+		RevertAt: h.revertAt(name),
+	}
+
+	if len(name) == 0 {
+		resp.Loggers = make(map[string]string)
+		for _, loggerName := range registry.list() {
+			resp.Loggers[loggerName] = registry.effective(loggerName).String()
+		}
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *levelHandler) putLevel(w http.ResponseWriter, r *http.Request, name string) {
+	var req levelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to decode request: %v", err))
+		return
+	}
+
+	level, err := ParseLevel(req.Level)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var ttl time.Duration
+	if len(req.TTL) > 0 {
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			h.writeError(w, http.StatusBadRequest, fmt.Errorf("not a valid TTL: %s", req.TTL))
+			return
+		}
+	}
+
+	registry, name, err := h.resolve(name)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	h.mux.Lock()
+	pendingRevert, pending := h.reverts[name]
+	if pending {
+		pendingRevert.timer.Stop()
+		delete(h.reverts, name)
+	}
+
+	if ttl > 0 {
+		revert := new(levelRevert)
+		if pending {
+			// consecutive temporary changes revert back to the permanent level.
+			revert.level, revert.explicit = pendingRevert.level, pendingRevert.explicit
+		} else {
+			revert.level, revert.explicit = registry.explicit(name)
+		}
+
+		revert.at = time.Now().Add(ttl)
+		revert.timer = time.AfterFunc(ttl, func() {
+			h.revert(registry, name, revert)
+		})
+
+		h.reverts[name] = revert
+	}
+
+	registry.set(name, level)
+	h.mux.Unlock()
+
+	h.writeJSON(w, http.StatusOK, levelResponse{
+		Logger:   name,
+		Level:    registry.effective(name).String(),
+		RevertAt: h.revertAt(name),
+	})
+}
+
+func (h *levelHandler) revert(registry *levelRegistry, name string, revert *levelRevert) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if h.reverts[name] != revert {
+		// superseded by another change
+		return
+	}
+
+	delete(h.reverts, name)
+
+	if revert.explicit {
+		registry.set(name, revert.level)
+	} else {
+		registry.unset(name)
+	}
+}
+
+func (h *levelHandler) revertAt(name string) *time.Time {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	revert, ok := h.reverts[name]
+	if !ok {
+		return nil
+	}
+
+	at := revert.at
+
+	return &at
+}
+
+// resolve finds the level registry of the logger and the name to control,
+// defaulting to the name of the logger itself.
+func (h *levelHandler) resolve(name string) (*levelRegistry, string, error) {
+	l, ok := h.logger.(*suplogger)
+	if !ok {
+		return nil, "", fmt.Errorf("logger %T doesn't support level control", h.logger)
+	}
+
+	l.initOnce()
+
+	if len(name) == 0 {
+		return l.levels, l.name, nil
+	} else if name == levelsWildcard {
+		return l.levels, "", nil
+	}
+
+	if !l.levels.known(name) {
+		return nil, "", fmt.Errorf("logger not found: %s", name)
+	}
+
+	return l.levels, name, nil
+}
+
+func (h *levelHandler) writeError(w http.ResponseWriter, status int, err error) {
+	h.writeJSON(w, status, levelErrorResponse{
+		Error: err.Error(),
+	})
+}
+
+func (h *levelHandler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
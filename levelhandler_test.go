@@ -0,0 +1,75 @@
+package suplog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelHandler(t *testing.T) {
+	logger := NewLogger(ioutil.Discard, nil)
+	logger.(LoggerConfigurator).SetLevel(InfoLevel)
+	logger.Named("db")
+
+	srv := httptest.NewServer(LevelHandler(logger))
+	defer srv.Close()
+
+	if level := getLevel(t, srv.URL+"?logger=db"); level != "info" {
+		t.Errorf("expected db to inherit info level, got %s", level)
+	}
+
+	putLevel(t, srv.URL+"?logger=db", `{"level":"debug","ttl":"100ms"}`, http.StatusOK)
+
+	if level := getLevel(t, srv.URL+"?logger=db"); level != "debug" {
+		t.Errorf("expected db level to be debug, got %s", level)
+	}
+
+	if level := getLevel(t, srv.URL); level != "info" {
+		t.Errorf("expected root level to stay info, got %s", level)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if level := getLevel(t, srv.URL+"?logger=db"); level != "info" {
+		t.Errorf("expected db level to be reverted to info, got %s", level)
+	}
+
+	putLevel(t, srv.URL+"?logger=db", `{"level":"loud"}`, http.StatusBadRequest)
+	putLevel(t, srv.URL+"?logger=http", `{"level":"debug"}`, http.StatusNotFound)
+}
+
+func getLevel(t *testing.T, url string) string {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body levelResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	return body.Level
+}
+
+func putLevel(t *testing.T, url, body string, status int) {
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != status {
+		t.Errorf("expected status %d for %s, got %d", status, body, resp.StatusCode)
+	}
+}
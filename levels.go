@@ -0,0 +1,206 @@
+package suplog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LoggerField is the field name that carries the name of a named logger.
+const LoggerField = "logger"
+
+// levelsWildcard denotes the root logger in a levels spec.
+const levelsWildcard = "*"
+
+// ParseLevels parses a per-logger levels spec, such as "db=debug,http=warn,*=info".
+// The wildcard name "*" sets the level of the root logger. A bare level
+// without a name is treated as the root level as well.
+func ParseLevels(spec string) (map[string]Level, error) {
+	levels := make(map[string]Level)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		name, levelName := levelsWildcard, part
+		if idx := strings.IndexByte(part, '='); idx >= 0 {
+			name = strings.TrimSpace(part[:idx])
+			levelName = strings.TrimSpace(part[idx+1:])
+		}
+
+		if len(name) == 0 {
+			return nil, fmt.Errorf("empty logger name in levels spec: %s", part)
+		}
+
+		level, err := ParseLevel(levelName)
+		if err != nil {
+			return nil, err
+		}
+
+		levels[name] = level
+	}
+
+	return levels, nil
+}
+
+// levelRegistry resolves effective levels of named loggers
+// hierarchically: "db.pool" inherits from "db", which inherits from the root level.
+type levelRegistry struct {
+	mux    sync.RWMutex
+	logger *logrus.Logger
+	root   Level
+	levels map[string]Level
+	names  map[string]struct{}
+}
+
+func newLevelRegistry(logger *logrus.Logger, root Level) *levelRegistry {
+	r := &levelRegistry{
+		logger: logger,
+		root:   root,
+		levels: make(map[string]Level),
+		names:  make(map[string]struct{}),
+	}
+
+	r.syncLogger()
+
+	return r
+}
+
+// register remembers the name of a named logger, so it could be listed.
+func (r *levelRegistry) register(name string) {
+	r.mux.RLock()
+	_, ok := r.names[name]
+	r.mux.RUnlock()
+
+	if ok {
+		return
+	}
+
+	r.mux.Lock()
+	r.names[name] = struct{}{}
+	r.mux.Unlock()
+}
+
+// known reports whether the name has been registered or configured.
+func (r *levelRegistry) known(name string) bool {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	if _, ok := r.names[name]; ok {
+		return true
+	}
+
+	_, ok := r.levels[name]
+
+	return ok
+}
+
+// list returns all registered and configured logger names, sorted.
+func (r *levelRegistry) list() []string {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	names := make([]string, 0, len(r.names)+len(r.levels))
+	for name := range r.names {
+		names = append(names, name)
+	}
+
+	for name := range r.levels {
+		if _, ok := r.names[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// explicit returns the level configured for exactly this name, if any.
+func (r *levelRegistry) explicit(name string) (Level, bool) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	if len(name) == 0 || name == levelsWildcard {
+		return r.root, true
+	}
+
+	level, ok := r.levels[name]
+
+	return level, ok
+}
+
+// effective returns the level of the closest configured ancestor of the name.
+func (r *levelRegistry) effective(name string) Level {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	if len(r.levels) == 0 {
+		return r.root
+	}
+
+	for len(name) > 0 {
+		if level, ok := r.levels[name]; ok {
+			return level
+		}
+
+		idx := strings.LastIndexByte(name, '.')
+		if idx < 0 {
+			break
+		}
+
+		name = name[:idx]
+	}
+
+	return r.root
+}
+
+func (r *levelRegistry) enabled(name string, level Level) bool {
+	return r.effective(name) >= level
+}
+
+// set configures level for the given name, empty name or the wildcard set the root level.
+func (r *levelRegistry) set(name string, level Level) {
+	r.mux.Lock()
+	if len(name) == 0 || name == levelsWildcard {
+		r.root = level
+	} else {
+		r.levels[name] = level
+	}
+	r.syncLogger()
+	r.mux.Unlock()
+}
+
+// unset removes the level configured for the name, so it inherits the level again.
+func (r *levelRegistry) unset(name string) {
+	r.mux.Lock()
+	delete(r.levels, name)
+	r.syncLogger()
+	r.mux.Unlock()
+}
+
+// setAll applies levels parsed by ParseLevels.
+func (r *levelRegistry) setAll(levels map[string]Level) {
+	for name, level := range levels {
+		r.set(name, level)
+	}
+}
+
+// syncLogger keeps the underlying logrus level at the most verbose configured level,
+// so logrus doesn't filter entries that are enabled for some named logger.
+// Must be called with the lock held.
+func (r *levelRegistry) syncLogger() {
+	maxLevel := r.root
+	for _, level := range r.levels {
+		if level > maxLevel {
+			maxLevel = level
+		}
+	}
+
+	r.logger.SetLevel(maxLevel)
+}
@@ -0,0 +1,63 @@
+package suplog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels("db=debug, http=warn,*=info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]Level{
+		"db":   DebugLevel,
+		"http": WarnLevel,
+		"*":    InfoLevel,
+	}
+
+	if len(levels) != len(expected) {
+		t.Fatalf("expected %d levels, got %v", len(expected), levels)
+	}
+
+	for name, level := range expected {
+		if levels[name] != level {
+			t.Errorf("expected %s for %s, got %s", level, name, levels[name])
+		}
+	}
+
+	if _, err := ParseLevels("db=loud"); err == nil {
+		t.Error("expected error for invalid level")
+	}
+}
+
+func TestNamedLevels(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, new(JSONFormatter))
+	logger.(LoggerConfigurator).SetLevel(InfoLevel)
+
+	db := logger.Named("db")
+	db.(LoggerConfigurator).SetLevel(DebugLevel)
+
+	pool := db.Named("pool")
+	http := logger.Named("http")
+
+	if pool.(LoggerConfigurator).GetLevel() != DebugLevel {
+		t.Errorf("expected db.pool to inherit debug level from db")
+	}
+
+	pool.Debugf("pool debug")
+	http.Debugf("http debug")
+	logger.Debugf("root debug")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected exactly one line, got: %s", out.String())
+	}
+
+	if !strings.Contains(lines[0], `"logger":"db.pool"`) {
+		t.Errorf("expected logger field in %s", lines[0])
+	}
+}
@@ -0,0 +1,178 @@
+package suplog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Flusher is implemented by hooks that deliver entries asynchronously.
+// Flush blocks until pending deliveries are done or ctx is done. Hooks are flushed
+// on Close and before exiting on Fatal.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Closer is implemented by hooks that hold resources, Close is called
+// after Flush once the logger is closed.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// AfterClosePolicy specifies what happens to entries logged after Close.
+// Such entries are counted anyway, see LoggedAfterClose.
+type AfterClosePolicy int
+
+const (
+	// AfterCloseStderr writes entries into stderr, hooks are not fired.
+	AfterCloseStderr AfterClosePolicy = iota
+	// AfterCloseDrop drops entries.
+	AfterCloseDrop
+)
+
+// ParseAfterClosePolicy takes a string policy name and returns the policy constant.
+func ParseAfterClosePolicy(name string) (policy AfterClosePolicy, err error) {
+	switch name {
+	case "stderr":
+		policy = AfterCloseStderr
+	case "drop":
+		policy = AfterCloseDrop
+	default:
+		err = fmt.Errorf("not a valid after close policy: %s", name)
+	}
+
+	return
+}
+
+const defaultFlushTimeout = 5 * time.Second
+
+// lifecycle is the closing state shared by a logger and all loggers derived from it.
+type lifecycle struct {
+	mux    sync.Mutex
+	closed int32
+
+	afterClose       AfterClosePolicy
+	loggedAfterClose uint64
+	flushTimeout     time.Duration
+}
+
+func newLifecycle(cfg *Config) *lifecycle {
+	life := &lifecycle{
+		afterClose:   cfg.AfterClose,
+		flushTimeout: cfg.FlushTimeout,
+	}
+
+	if life.flushTimeout <= 0 {
+		life.flushTimeout = defaultFlushTimeout
+	}
+
+	return life
+}
+
+func (life *lifecycle) isClosed() bool {
+	return atomic.LoadInt32(&life.closed) == 1
+}
+
+// writeClosed handles an entry logged after Close, according to the policy.
+func (l *suplogger) writeClosed(entry *logrus.Entry, level Level, msg string) {
+	atomic.AddUint64(&l.life.loggedAfterClose, 1)
+
+	if l.life.afterClose == AfterCloseDrop {
+		return
+	}
+
+	formatter := l.logger.Formatter
+	if f, ok := formatter.(*levelFormatter); ok {
+		formatter = f.Formatter
+	}
+
+	e := entry.Dup()
+	e.Level = level
+	e.Message = msg
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	serialized, err := formatter.Format(e)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to obtain reader, %v\n", err)
+		return
+	}
+
+	_, _ = os.Stderr.Write(serialized)
+}
+
+// LoggedAfterClose returns the amount of entries logged after Close.
+func (l *suplogger) LoggedAfterClose() uint64 {
+	l.initOnce()
+	return atomic.LoadUint64(&l.life.loggedAfterClose)
+}
+
+// flushHooks flushes all hooks implementing Flusher, closing hooks implementing
+// Closer if requested, waiting no longer than the flush timeout.
+func (l *suplogger) flushHooks(closeHooks bool) {
+	hooks := uniqueHooks(l.logger.Hooks)
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.life.flushTimeout)
+	defer cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for _, h := range hooks {
+			if flusher, ok := h.(Flusher); ok {
+				if err := flusher.Flush(ctx); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to flush log hook, %v\n", err)
+				}
+			}
+
+			if !closeHooks {
+				continue
+			}
+
+			if closer, ok := h.(Closer); ok {
+				if err := closer.Close(ctx); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to close log hook, %v\n", err)
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		fmt.Fprintf(os.Stderr, "Failed to flush log hooks, %v\n", ctx.Err())
+	}
+}
+
+// uniqueHooks returns hooks registered on any level, each hook once.
+func uniqueHooks(levelHooks LevelHooks) []Hook {
+	var hooks []Hook
+
+	seen := make(map[Hook]bool)
+
+	for _, level := range logrus.AllLevels {
+		for _, h := range levelHooks[level] {
+			if reflect.TypeOf(h).Comparable() {
+				if seen[h] {
+					continue
+				}
+
+				seen[h] = true
+			}
+
+			hooks = append(hooks, h)
+		}
+	}
+
+	return hooks
+}
@@ -0,0 +1,127 @@
+package suplog
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type lifecycleHook struct {
+	fired   int
+	flushed int
+	closed  int
+	block   chan struct{}
+}
+
+func (h *lifecycleHook) Levels() []Level {
+	return logrus.AllLevels
+}
+
+func (h *lifecycleHook) Fire(*Entry) error {
+	h.fired++
+	return nil
+}
+
+func (h *lifecycleHook) Flush(ctx context.Context) error {
+	if h.block != nil {
+		<-h.block
+	}
+
+	h.flushed++
+
+	return nil
+}
+
+func (h *lifecycleHook) Close(ctx context.Context) error {
+	h.closed++
+	return nil
+}
+
+func TestCloseFlushesHooks(t *testing.T) {
+	hook := new(lifecycleHook)
+	out := new(bytes.Buffer)
+
+	logger, err := NewLoggerWithConfig(&Config{
+		Level:      DebugLevel,
+		AfterClose: AfterCloseDrop,
+	}, hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.(LoggerConfigurator).SetOutput(out)
+	named := logger.Named("db")
+
+	logger.Info("before close")
+	logger.(interface{ Close() error }).Close()
+	logger.(interface{ Close() error }).Close()
+
+	if hook.flushed != 1 || hook.closed != 1 {
+		t.Errorf("expected hook to be flushed and closed once, got %d and %d", hook.flushed, hook.closed)
+	}
+
+	named.Info("after close")
+	named.Warning("after close")
+
+	if hook.fired != 1 {
+		t.Errorf("expected hook not to fire after close, fired %d times", hook.fired)
+	}
+
+	if count := logger.LoggedAfterClose(); count != 2 {
+		t.Errorf("expected 2 entries logged after close, got %d", count)
+	}
+
+	if bytes.Contains(out.Bytes(), []byte("after close")) {
+		t.Errorf("expected entries after close to be dropped, got %s", out.String())
+	}
+}
+
+func TestFatalFlushesHooks(t *testing.T) {
+	hook := new(lifecycleHook)
+	exitCode := -1
+
+	logger, err := NewLoggerWithConfig(&Config{
+		Level: DebugLevel,
+		ExitFunc: func(code int) {
+			exitCode = code
+		},
+	}, hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.(LoggerConfigurator).SetOutput(new(bytes.Buffer))
+	logger.Fatal("exiting")
+
+	if exitCode != 1 || hook.flushed != 1 || hook.closed != 0 {
+		t.Errorf("expected hook to be flushed before exit, got exit code %d, flushed %d, closed %d",
+			exitCode, hook.flushed, hook.closed)
+	}
+}
+
+func TestFlushTimeout(t *testing.T) {
+	hook := &lifecycleHook{
+		block: make(chan struct{}),
+	}
+	defer close(hook.block)
+
+	logger, err := NewLoggerWithConfig(&Config{
+		Level:        DebugLevel,
+		FlushTimeout: 50 * time.Millisecond,
+	}, hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.(LoggerConfigurator).SetOutput(new(bytes.Buffer))
+
+	start := time.Now()
+	logger.(interface{ Close() error }).Close()
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected close to be bounded by flush timeout, took %v", elapsed)
+	}
+}
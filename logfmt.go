@@ -0,0 +1,145 @@
+package suplog
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	stackHook "github.com/xlab/suplog/hooks/stack"
+)
+
+// LogfmtFormatter formats entries as strict logfmt: time, level and msg
+// followed by fields sorted by key. Keys are sanitized, values are quoted
+// when they contain spaces, quotes, '=' or control characters, so each
+// entry stays on a single line. Fields clashing with time, level or msg
+// are prefixed with "fields.", like logrus does. Stacks of the stack hook
+// are rendered as "func file:line" frames separated by "; ".
+type LogfmtFormatter struct {
+	// TimestampFormat sets the format used for timestamps, time.RFC3339Nano by default.
+	TimestampFormat string
+	// DisableTimestamp disables the timestamp.
+	DisableTimestamp bool
+}
+
+// Format renders a single log entry.
+func (f *LogfmtFormatter) Format(e *Entry) ([]byte, error) {
+	b := e.Buffer
+	if b == nil {
+		b = new(bytes.Buffer)
+	}
+
+	if !f.DisableTimestamp {
+		timestampFormat := f.TimestampFormat
+		if len(timestampFormat) == 0 {
+			timestampFormat = time.RFC3339Nano
+		}
+
+		writeLogfmtPair(b, "time", e.Time.Format(timestampFormat))
+		b.WriteByte(' ')
+	}
+
+	writeLogfmtPair(b, "level", e.Level.String())
+	b.WriteByte(' ')
+	writeLogfmtPair(b, "msg", strings.TrimSuffix(e.Message, "\n"))
+
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := k
+		switch key {
+		case "time", "level", "msg":
+			key = "fields." + key
+		}
+
+		b.WriteByte(' ')
+		writeLogfmtPair(b, key, logfmtValue(e.Data[k]))
+	}
+
+	b.WriteByte('\n')
+
+	return b.Bytes(), nil
+}
+
+func writeLogfmtPair(b *bytes.Buffer, key, value string) {
+	writeLogfmtKey(b, key)
+	b.WriteByte('=')
+
+	if logfmtNeedsQuoting(value) {
+		b.WriteString(strconv.Quote(value))
+	} else {
+		b.WriteString(value)
+	}
+}
+
+// writeLogfmtKey writes the key, replacing characters not allowed in logfmt keys with '_'.
+func writeLogfmtKey(b *bytes.Buffer, key string) {
+	if len(key) == 0 {
+		b.WriteByte('_')
+		return
+	}
+
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			b.WriteByte('_')
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+}
+
+func logfmtNeedsQuoting(value string) bool {
+	if len(value) == 0 {
+		return true
+	}
+
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func logfmtValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case nil:
+		return "null"
+	case []stackHook.Frame:
+		return logfmtFrames(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// logfmtFrames renders frames of the stack hook on a single line,
+// as "func file:line" separated by "; ".
+func logfmtFrames(frames []stackHook.Frame) string {
+	var b strings.Builder
+	for i, frame := range frames {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+
+		fmt.Fprintf(&b, "%s %s:%d", frame.Func, frame.File, frame.Line)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,409 @@
+// Package file provides a rotating file output for suplog, with compression
+// and retention of rotated segments.
+package file
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Options allows to set additional Writer options.
+type Options struct {
+	// MaxSize rotates the file once it would grow beyond this size in bytes, 0 disables.
+	MaxSize int64
+	// Interval rotates the file on each interval boundary, e.g. 24h rotates daily, 0 disables.
+	Interval time.Duration
+	// Compress enables gzip compression of rotated segments.
+	Compress bool
+	// MaxAge removes rotated segments older than this, 0 keeps all.
+	MaxAge time.Duration
+	// MaxBackups limits the number of rotated segments kept, 0 keeps all.
+	MaxBackups int
+	// Perm sets permissions of the created files, 0644 by default.
+	Perm os.FileMode
+	// DisableSIGHUP disables reopening of the file on SIGHUP. By default the file
+	// is reopened, so external tools like logrotate can move it away.
+	DisableSIGHUP bool
+}
+
+func checkOptions(opt *Options) *Options {
+	if opt == nil {
+		opt = &Options{}
+	}
+
+	if opt.Perm == 0 {
+		opt.Perm = 0644
+	}
+
+	return opt
+}
+
+// backupTimeFormat is the timestamp layout in names of rotated segments.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// compressSuffix is the suffix of compressed rotated segments.
+const compressSuffix = ".gz"
+
+// Writer is an io.WriteCloser that writes into a file, rotating it by size and time.
+type Writer struct {
+	opt  *Options
+	path string
+
+	mux        sync.Mutex
+	file       *os.File
+	size       int64
+	nextRotate time.Time
+	closed     bool
+
+	// background compression and cleanup of rotated segments
+	millWg  sync.WaitGroup
+	millMux sync.Mutex
+
+	signals chan os.Signal
+	stop    chan struct{}
+
+	now func() time.Time
+}
+
+// New opens or creates the file at path for appending.
+func New(path string, opt *Options) (*Writer, error) {
+	w := &Writer{
+		opt:  checkOptions(opt),
+		path: path,
+		stop: make(chan struct{}),
+		now:  time.Now,
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	if !w.opt.DisableSIGHUP {
+		w.signals = make(chan os.Signal, 1)
+		signal.Notify(w.signals, syscall.SIGHUP)
+
+		go w.handleSignals()
+	}
+
+	return w, nil
+}
+
+func (w *Writer) handleSignals() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.signals:
+			if err := w.Reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to reopen log file, %v\n", err)
+			}
+		}
+	}
+}
+
+// open opens the file, must be called with lock held.
+func (w *Writer) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return fmt.Errorf("failed to create log dir: %w", err)
+	}
+
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, w.opt.Perm)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	w.file = f
+	w.size = info.Size()
+
+	if w.opt.Interval > 0 {
+		w.nextRotate = w.now().Truncate(w.opt.Interval).Add(w.opt.Interval)
+	}
+
+	return nil
+}
+
+// Write writes p into the file, rotating it first if needed.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	if w.needsRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+func (w *Writer) needsRotate(n int64) bool {
+	if w.opt.MaxSize > 0 && w.size > 0 && w.size+n > w.opt.MaxSize {
+		return true
+	}
+
+	if w.opt.Interval > 0 && !w.now().Before(w.nextRotate) {
+		return true
+	}
+
+	return false
+}
+
+// Rotate closes the current file, moves it aside and opens a new one.
+func (w *Writer) Rotate() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+
+	return w.rotate()
+}
+
+// rotate must be called with lock held.
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+
+	now := w.now()
+	backupPath := w.backupPath(now)
+	if err := os.Rename(w.path, backupPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rename log file: %w", err)
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.millWg.Add(1)
+	go func() {
+		defer w.millWg.Done()
+		w.mill(backupPath, now)
+	}()
+
+	return nil
+}
+
+// Reopen closes and opens the file at the same path,
+// to be used after the file has been moved by an external tool.
+func (w *Writer) Reopen() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+
+	return w.open()
+}
+
+// Sync commits the file contents to stable storage.
+func (w *Writer) Sync() error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+
+	return w.file.Sync()
+}
+
+// Close waits for compression of rotated segments and closes the file.
+func (w *Writer) Close() error {
+	w.mux.Lock()
+	if w.closed {
+		w.mux.Unlock()
+		return nil
+	}
+
+	w.closed = true
+	close(w.stop)
+
+	if w.signals != nil {
+		signal.Stop(w.signals)
+	}
+
+	err := w.file.Sync()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.mux.Unlock()
+
+	w.millWg.Wait()
+
+	return err
+}
+
+// backupPath returns the name of the rotated segment, e.g. app-2006-01-02T15-04-05.000.log
+// Timestamps are in UTC.
+func (w *Writer) backupPath(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+
+	return filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+func (w *Writer) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(w.path)
+	base := filepath.Base(w.path)
+	ext = filepath.Ext(base)
+	prefix = strings.TrimSuffix(base, ext) + "-"
+
+	return dir, prefix, ext
+}
+
+// mill compresses the rotated segment and removes segments out of retention.
+// Runs in background, concurrent runs are serialized.
+func (w *Writer) mill(backupPath string, now time.Time) {
+	w.millMux.Lock()
+	defer w.millMux.Unlock()
+
+	if w.opt.Compress {
+		if err := compressFile(backupPath, w.opt.Perm); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to compress log file, %v\n", err)
+		}
+	}
+
+	if err := w.cleanup(now); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to remove old log files, %v\n", err)
+	}
+}
+
+type backupFile struct {
+	path string
+	t    time.Time
+}
+
+// cleanup removes rotated segments according to MaxAge and MaxBackups.
+func (w *Writer) cleanup(now time.Time) error {
+	if w.opt.MaxAge <= 0 && w.opt.MaxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := w.listBackups()
+	if err != nil {
+		return err
+	}
+
+	// newest first
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].t.After(backups[j].t)
+	})
+
+	cutoff := now.Add(-w.opt.MaxAge)
+
+	for i, b := range backups {
+		expired := w.opt.MaxAge > 0 && b.t.Before(cutoff)
+		excess := w.opt.MaxBackups > 0 && i >= w.opt.MaxBackups
+
+		if expired || excess {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) listBackups() ([]backupFile, error) {
+	dir, prefix, ext := w.nameParts()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backupFile
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		ts := strings.TrimPrefix(name, prefix)
+		ts = strings.TrimSuffix(ts, compressSuffix)
+
+		if !strings.HasSuffix(ts, ext) {
+			continue
+		}
+
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(ts, ext))
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, backupFile{
+			path: filepath.Join(dir, name),
+			t:    t,
+		})
+	}
+
+	return backups, nil
+}
+
+// compressFile gzips the file and removes the original.
+func compressFile(path string, perm os.FileMode) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(path + compressSuffix)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return err
+	}
+
+	if err = gz.Close(); err != nil {
+		return err
+	}
+
+	if err = dst.Close(); err != nil {
+		return err
+	}
+
+	src.Close()
+
+	return os.Remove(path)
+}
@@ -0,0 +1,118 @@
+package file
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := New(path, &Options{
+		MaxSize:       10,
+		Compress:      true,
+		MaxBackups:    2,
+		DisableSIGHUP: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Date(2022, 7, 20, 11, 0, 0, 0, time.UTC)
+	w.now = func() time.Time {
+		ts = ts.Add(time.Second)
+		return ts
+	}
+
+	for _, line := range []string{"line one\n", "line two\n", "line three\n", "line four\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "line four\n" {
+		t.Errorf("expected only the latest line in the current file, got %q", data)
+	}
+
+	backups, err := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 compressed backups to be retained, got %v", backups)
+	}
+
+	if content := readGzip(t, backups[len(backups)-1]); content != "line three\n" {
+		t.Errorf("expected the latest backup to contain line three, got %q", content)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := New(path, &Options{
+		DisableSIGHUP: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("before\n"))
+
+	// emulate logrotate moving the file away
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+
+	w.Write([]byte("after\n"))
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "after\n" {
+		t.Errorf("expected reopened file to contain new entries only, got %q", data)
+	}
+}
+
+func readGzip(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
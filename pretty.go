@@ -0,0 +1,307 @@
+package suplog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	stackHook "github.com/xlab/suplog/hooks/stack"
+)
+
+const (
+	defaultPrettyTimestampFormat = "15:04:05.000"
+	defaultPrettyMessageWidth    = 40
+)
+
+// ANSI escape codes used by PrettyFormatter.
+const (
+	colorReset = "\x1b[0m"
+	colorDim   = "\x1b[2m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorBlue  = "\x1b[34m"
+	colorCyan  = "\x1b[36m"
+)
+
+// PrettyFormatter formats entries for developer terminals: a colored level badge,
+// a short timestamp, the message followed by aligned fields, while fn and src
+// of the debug hook are dimmed. Multi-line errors, values and stacks are indented
+// on the following lines. Colors are enabled for terminals, unless NO_COLOR is set,
+// detected once on the first entry.
+// Success entries are marked with a green check, Notification entries with a blue bullet.
+type PrettyFormatter struct {
+	// TimestampFormat sets the format used for timestamps, 15:04:05.000 by default.
+	TimestampFormat string
+	// DisableTimestamp disables the timestamp.
+	DisableTimestamp bool
+	// ForceColors enables colors even if the output is not a terminal.
+	ForceColors bool
+	// DisableColors disables colors.
+	DisableColors bool
+	// MessageWidth pads the message, so fields of consecutive entries are aligned.
+	MessageWidth int
+	// CLIMode formats entries for command line tools: no timestamps,
+	// and no badges for Info entries.
+	CLIMode bool
+
+	colorsInitOnce sync.Once
+	colors         bool
+}
+
+// prettyTrailingFields are shown dimmed at the end of the line.
+var prettyTrailingFields = []string{"fn", "src"}
+
+// Format renders a single log entry.
+func (f *PrettyFormatter) Format(e *Entry) ([]byte, error) {
+	f.colorsInitOnce.Do(func() {
+		f.colors = f.isColored(e)
+	})
+
+	colors := f.colors
+
+	b := e.Buffer
+	if b == nil {
+		b = new(bytes.Buffer)
+	}
+
+	kind, _ := e.Data[KindField].(string)
+
+	if !f.DisableTimestamp && !f.CLIMode {
+		timestampFormat := f.TimestampFormat
+		if len(timestampFormat) == 0 {
+			timestampFormat = defaultPrettyTimestampFormat
+		}
+
+		f.writeColored(b, colors, colorDim, e.Time.Format(timestampFormat))
+		b.WriteByte(' ')
+	}
+
+	if !f.CLIMode || e.Level != InfoLevel {
+		f.writeBadge(b, colors, e.Level)
+		b.WriteByte(' ')
+	}
+
+	switch kind {
+	case KindSuccess:
+		f.writeColored(b, colors, colorGreen, "✔")
+		b.WriteByte(' ')
+	case KindNotification:
+		f.writeColored(b, colors, colorBlue, "•")
+		b.WriteByte(' ')
+	}
+
+	msg := strings.TrimSuffix(e.Message, "\n")
+	b.WriteString(msg)
+
+	var (
+		keys      = make([]string, 0, len(e.Data))
+		multiline []string
+	)
+
+	for k := range e.Data {
+		switch k {
+		case "fn", "src", stackHook.Field:
+			continue
+		case KindField:
+			if len(kind) > 0 {
+				continue
+			}
+		}
+
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	// error goes first
+	for i, k := range keys {
+		if k == logrus.ErrorKey {
+			copy(keys[1:i+1], keys[:i])
+			keys[0] = k
+		}
+	}
+
+	inline := keys[:0]
+
+	for _, k := range keys {
+		if strings.Contains(prettyValue(e.Data[k]), "\n") {
+			multiline = append(multiline, k)
+		} else {
+			inline = append(inline, k)
+		}
+	}
+
+	_, hasFn := e.Data["fn"]
+	_, hasSrc := e.Data["src"]
+
+	if len(inline) > 0 || hasFn || hasSrc {
+		messageWidth := f.MessageWidth
+		if messageWidth == 0 {
+			messageWidth = defaultPrettyMessageWidth
+		}
+
+		if pad := messageWidth - len(msg); pad > 0 {
+			b.WriteString(strings.Repeat(" ", pad))
+		}
+	}
+
+	for _, k := range inline {
+		value := prettyValue(e.Data[k])
+
+		b.WriteByte(' ')
+
+		if k == logrus.ErrorKey {
+			f.writeColored(b, colors, colorRed, k+"="+value)
+			continue
+		}
+
+		f.writeColored(b, colors, colorCyan, k)
+		b.WriteByte('=')
+		b.WriteString(value)
+	}
+
+	for _, k := range prettyTrailingFields {
+		if v, ok := e.Data[k]; ok {
+			b.WriteByte(' ')
+			f.writeColored(b, colors, colorDim, k+"="+fmt.Sprint(v))
+		}
+	}
+
+	b.WriteByte('\n')
+
+	for _, k := range multiline {
+		color := colorCyan
+		if k == logrus.ErrorKey {
+			color = colorRed
+		}
+
+		b.WriteString("    ")
+		f.writeColored(b, colors, color, k+":")
+		b.WriteByte('\n')
+
+		for _, line := range strings.Split(strings.TrimSuffix(fmt.Sprint(e.Data[k]), "\n"), "\n") {
+			b.WriteString("        ")
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+
+	if frames, ok := e.Data[stackHook.Field].([]stackHook.Frame); ok {
+		for _, frame := range frames {
+			b.WriteString("    ")
+			b.WriteString(frame.Func)
+			b.WriteString("\n        ")
+			f.writeColored(b, colors, colorDim, frame.File+":"+strconv.Itoa(frame.Line))
+			b.WriteByte('\n')
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+func (f *PrettyFormatter) writeBadge(b *bytes.Buffer, colors bool, level Level) {
+	text := strings.ToUpper(level.String())
+	if len(text) > 4 {
+		text = text[:4]
+	}
+
+	if !colors {
+		fmt.Fprintf(b, "%-4s", text)
+		return
+	}
+
+	var color string
+
+	switch level {
+	case TraceLevel, DebugLevel:
+		color = "\x1b[30;47m"
+	case InfoLevel:
+		color = "\x1b[30;42m"
+	case WarnLevel:
+		color = "\x1b[30;43m"
+	case ErrorLevel:
+		color = "\x1b[37;41m"
+	default:
+		color = "\x1b[37;45m"
+	}
+
+	fmt.Fprintf(b, "%s %-4s %s", color, text, colorReset)
+}
+
+func (f *PrettyFormatter) writeColored(b *bytes.Buffer, colors bool, color, s string) {
+	if !colors {
+		b.WriteString(s)
+		return
+	}
+
+	b.WriteString(color)
+	b.WriteString(s)
+	b.WriteString(colorReset)
+}
+
+// isColored reports whether colors are enabled for the entry output.
+func (f *PrettyFormatter) isColored(e *Entry) bool {
+	if f.DisableColors {
+		return false
+	} else if f.ForceColors {
+		return true
+	} else if len(os.Getenv("NO_COLOR")) > 0 {
+		return false
+	}
+
+	if e.Logger == nil {
+		return false
+	}
+
+	return isTerminal(e.Logger.Out)
+}
+
+// isTerminal reports whether the output is a character device, e.g. a terminal.
+// For async output the underlying writer is checked.
+func isTerminal(out io.Writer) bool {
+	if async, ok := asyncOutput(out); ok {
+		return async.isTerminal()
+	}
+
+	file, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// prettyValue formats the field value, quoting strings where needed.
+func prettyValue(v interface{}) string {
+	var s string
+
+	switch value := v.(type) {
+	case string:
+		s = value
+	case error:
+		s = value.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if strings.Contains(s, "\n") {
+		return s
+	}
+
+	if len(s) == 0 || strings.ContainsAny(s, " =\"\t") {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
@@ -0,0 +1,151 @@
+package suplog
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	stackHook "github.com/xlab/suplog/hooks/stack"
+)
+
+func TestPrettyFormatter(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, &PrettyFormatter{
+		DisableTimestamp: true,
+		MessageWidth:     20,
+	})
+
+	logger.WithFields(Fields{
+		"user":  "max",
+		"query": "select 1",
+		"src":   "app/main.go:12",
+		"fn":    "main",
+		"error": errors.New("timeout"),
+	}).Warning("query failed")
+
+	logger.WithFields(Fields{
+		"error": errors.New("first line\nsecond line"),
+		stackHook.Field: []stackHook.Frame{{
+			Func: "main.main",
+			File: "app/main.go",
+			Line: 12,
+		}},
+	}).Error("multiline")
+
+	exp := "WARN query failed         error=timeout query=\"select 1\" user=max fn=main src=app/main.go:12\n" +
+		"ERRO multiline\n" +
+		"    error:\n" +
+		"        first line\n" +
+		"        second line\n" +
+		"    main.main\n" +
+		"        app/main.go:12\n"
+
+	if out.String() != exp {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", out.String(), exp)
+	}
+}
+
+func TestPrettyFormatterColors(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, &PrettyFormatter{
+		ForceColors: true,
+	})
+
+	logger.WithField("src", "app/main.go:12").Info("colored")
+
+	if !strings.Contains(out.String(), "\x1b[30;42m INFO \x1b[0m colored") {
+		t.Errorf("expected colored badge, got %q", out.String())
+	}
+
+	if !strings.Contains(out.String(), colorDim+"src=app/main.go:12"+colorReset) {
+		t.Errorf("expected dimmed src, got %q", out.String())
+	}
+
+	setenv(t, "NO_COLOR", "1")
+	out.Reset()
+
+	logger.(LoggerConfigurator).SetFormatter(new(PrettyFormatter))
+	logger.Info("plain")
+
+	if strings.Contains(out.String(), "\x1b[") {
+		t.Errorf("expected no colors with NO_COLOR, got %q", out.String())
+	}
+}
+
+func TestPrettyFormatterAsyncTerminal(t *testing.T) {
+	// the null device is a character device, just like terminals
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	if !isTerminal(devNull) {
+		t.Skip("null device is not a character device")
+	}
+
+	setenv(t, "NO_COLOR", "")
+
+	logger, err := NewLoggerWithConfig(&Config{
+		Level: InfoLevel,
+		Async: &AsyncOptions{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logger.(*suplogger).Close()
+
+	logger.(LoggerConfigurator).SetOutput(devNull)
+
+	entry := &Entry{
+		Logger: logger.(*suplogger).logger,
+	}
+
+	if !new(PrettyFormatter).isColored(entry) {
+		t.Error("expected colors for async output into a terminal")
+	}
+
+	logger.(LoggerConfigurator).SetOutput(new(bytes.Buffer))
+
+	if new(PrettyFormatter).isColored(entry) {
+		t.Error("expected no colors for async output into a buffer")
+	}
+}
+
+func TestPrettyFormatterColorsDetectedOnce(t *testing.T) {
+	// the null device is a character device, just like terminals
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	if !isTerminal(devNull) {
+		t.Skip("null device is not a character device")
+	}
+
+	setenv(t, "NO_COLOR", "")
+
+	f := new(PrettyFormatter)
+	entry := &Entry{
+		Logger: &logrus.Logger{
+			Out: devNull,
+		},
+		Level:   InfoLevel,
+		Message: "colored",
+	}
+
+	if out, _ := f.Format(entry); !bytes.Contains(out, []byte("\x1b[")) {
+		t.Fatalf("expected colors for a terminal, got %q", out)
+	}
+
+	setenv(t, "NO_COLOR", "1")
+	entry.Logger.Out = new(bytes.Buffer)
+
+	if out, _ := f.Format(entry); !bytes.Contains(out, []byte("\x1b[")) {
+		t.Errorf("expected colors detected on the first entry only, got %q", out)
+	}
+}
@@ -0,0 +1,253 @@
+// Package sampler implements per-call-site sampling of log entries, used to cap
+// log volume of hot loops. Hooks only see entries kept by the logger sampler.
+package sampler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// KeyBy selects how entries are grouped for sampling.
+type KeyBy int
+
+const (
+	// KeyByCaller groups entries by their call site.
+	KeyByCaller KeyBy = iota
+	// KeyByMessage groups entries by their message template.
+	KeyByMessage
+)
+
+// ParseKeyBy takes a string key name and returns the KeyBy constant.
+func ParseKeyBy(name string) (keyBy KeyBy, err error) {
+	switch strings.ToLower(name) {
+	case "caller":
+		keyBy = KeyByCaller
+	case "message":
+		keyBy = KeyByMessage
+	default:
+		err = fmt.Errorf("not a valid sampling key: %s", name)
+	}
+
+	return
+}
+
+// Rule logs the First entries per Interval for each key, and then every
+// Thereafter-th entry. Zero Thereafter drops all entries after the First.
+type Rule struct {
+	First      int
+	Thereafter int
+	Interval   time.Duration
+}
+
+// Options allows to set Sampler options.
+type Options struct {
+	// Rules specifies sampling rules per level, levels without a rule are not sampled.
+	// Panic and Fatal entries are never sampled.
+	Rules map[logrus.Level]Rule
+	// KeyBy selects how entries are grouped.
+	KeyBy KeyBy
+	// MaxKeys limits the amount of tracked keys, 10000 by default. Once exceeded,
+	// entries with new keys are sampled together, sharing a counter per level.
+	MaxKeys int
+}
+
+const defaultMaxKeys = 10000
+
+// ParseRules parses a sampling rules spec, where each rule is specified as
+// level=first:thereafter:interval, e.g. "debug=100:100:1s,warn=10:1000:1m".
+func ParseRules(spec string) (map[logrus.Level]Rule, error) {
+	rules := make(map[logrus.Level]Rule)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		idx := strings.IndexByte(part, '=')
+		if idx < 0 {
+			return nil, fmt.Errorf("no level in sampling rule: %s", part)
+		}
+
+		level, err := logrus.ParseLevel(strings.TrimSpace(part[:idx]))
+		if err != nil {
+			return nil, err
+		}
+
+		params := strings.Split(part[idx+1:], ":")
+		if len(params) != 3 {
+			return nil, fmt.Errorf("sampling rule must be level=first:thereafter:interval: %s", part)
+		}
+
+		var rule Rule
+
+		if rule.First, err = strconv.Atoi(params[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse first in sampling rule %s: %w", part, err)
+		}
+
+		if rule.Thereafter, err = strconv.Atoi(params[1]); err != nil {
+			return nil, fmt.Errorf("failed to parse thereafter in sampling rule %s: %w", part, err)
+		}
+
+		if rule.Interval, err = time.ParseDuration(params[2]); err != nil {
+			return nil, fmt.Errorf("failed to parse interval in sampling rule %s: %w", part, err)
+		}
+
+		rules[level] = rule
+	}
+
+	return rules, nil
+}
+
+// Sampler decides which entries are logged. It is safe for concurrent use.
+type Sampler struct {
+	opt *Options
+
+	mux       sync.Mutex
+	counters  map[key]*counter
+	lastSweep time.Time
+	// sweepInterval is the shortest rule interval, counters are swept for expired windows
+	// that often. Zero when no rule has an interval, so counters are never expired.
+	sweepInterval time.Duration
+
+	now func() time.Time
+}
+
+type key struct {
+	level    logrus.Level
+	pc       uintptr
+	message  string
+	overflow bool
+}
+
+type counter struct {
+	windowStart time.Time
+	n           int
+	dropped     uint64
+}
+
+// New creates a new sampler with provided options.
+func New(opt *Options) *Sampler {
+	if opt == nil {
+		opt = &Options{}
+	}
+
+	if opt.MaxKeys <= 0 {
+		opt.MaxKeys = defaultMaxKeys
+	}
+
+	s := &Sampler{
+		opt:      opt,
+		counters: make(map[key]*counter),
+		now:      time.Now,
+	}
+
+	for _, rule := range opt.Rules {
+		if rule.Interval > 0 && (s.sweepInterval == 0 || rule.Interval < s.sweepInterval) {
+			s.sweepInterval = rule.Interval
+		}
+	}
+
+	return s
+}
+
+// Enabled reports whether entries on the level are sampled at all,
+// so the caller could skip discovering the call site.
+func (s *Sampler) Enabled(level logrus.Level) bool {
+	if level <= logrus.FatalLevel {
+		return false
+	}
+
+	_, ok := s.opt.Rules[level]
+	return ok
+}
+
+// KeyBy returns how entries are grouped by this sampler.
+func (s *Sampler) KeyBy() KeyBy {
+	return s.opt.KeyBy
+}
+
+// Sample reports whether the entry should be logged, grouping entries by the
+// call site pc or by message template, depending on KeyBy. The template should
+// not contain formatted values, e.g. a format string, otherwise each distinct
+// message is sampled separately. When the entry is logged, sampled is the amount
+// of entries dropped since the previous one.
+func (s *Sampler) Sample(level logrus.Level, pc uintptr, template string) (ok bool, sampled uint64) {
+	if !s.Enabled(level) {
+		return true, 0
+	}
+
+	rule := s.opt.Rules[level]
+
+	k := key{
+		level: level,
+	}
+
+	if s.opt.KeyBy == KeyByMessage {
+		k.message = template
+	} else {
+		k.pc = pc
+	}
+
+	now := s.now()
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.sweepInterval > 0 && now.Sub(s.lastSweep) >= s.sweepInterval {
+		s.sweep(now)
+	}
+
+	c, found := s.counters[k]
+	if !found && len(s.counters) >= s.opt.MaxKeys {
+		k = key{
+			level:    level,
+			overflow: true,
+		}
+
+		c, found = s.counters[k]
+	}
+
+	if !found {
+		c = &counter{
+			windowStart: now,
+		}
+
+		s.counters[k] = c
+	} else if rule.Interval > 0 && now.Sub(c.windowStart) >= rule.Interval {
+		c.windowStart = now
+		c.n = 0
+	}
+
+	c.n++
+
+	if c.n > rule.First && (rule.Thereafter <= 0 || (c.n-rule.First)%rule.Thereafter != 0) {
+		c.dropped++
+		return false, 0
+	}
+
+	sampled, c.dropped = c.dropped, 0
+
+	return true, sampled
+}
+
+// sweep removes counters with expired windows, counters of levels without
+// an interval are kept, as well as counters with dropped entries not reported yet.
+func (s *Sampler) sweep(now time.Time) {
+	s.lastSweep = now
+
+	for k, c := range s.counters {
+		if c.dropped > 0 {
+			continue
+		}
+
+		if interval := s.opt.Rules[k.level].Interval; interval > 0 && now.Sub(c.windowStart) >= interval {
+			delete(s.counters, k)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSample(t *testing.T) {
+	s := New(&Options{
+		Rules: map[logrus.Level]Rule{
+			logrus.DebugLevel: {
+				First:      2,
+				Thereafter: 3,
+				Interval:   time.Second,
+			},
+		},
+	})
+
+	ts := time.Date(2022, 7, 20, 11, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		return ts
+	}
+
+	var logged []int
+	var sampled []uint64
+
+	for i := 1; i <= 8; i++ {
+		if ok, n := s.Sample(logrus.DebugLevel, 1, ""); ok {
+			logged = append(logged, i)
+			sampled = append(sampled, n)
+		}
+	}
+
+	// first 2, then every 3rd: 5, 8
+	expectInts(t, logged, []int{1, 2, 5, 8})
+	expectUints(t, sampled, []uint64{0, 0, 2, 2})
+
+	if ok, _ := s.Sample(logrus.DebugLevel, 2, ""); !ok {
+		t.Errorf("expected another call site to be sampled separately")
+	}
+
+	if ok, _ := s.Sample(logrus.InfoLevel, 1, ""); !ok {
+		t.Errorf("expected levels without rules to be logged")
+	}
+
+	ts = ts.Add(time.Second)
+
+	if ok, _ := s.Sample(logrus.DebugLevel, 1, ""); !ok {
+		t.Errorf("expected counter to reset after interval")
+	}
+}
+
+func TestSampleFatalAndPanic(t *testing.T) {
+	s := New(&Options{
+		Rules: map[logrus.Level]Rule{
+			logrus.PanicLevel: {First: 0},
+			logrus.FatalLevel: {First: 0},
+		},
+	})
+
+	for _, level := range []logrus.Level{logrus.PanicLevel, logrus.FatalLevel} {
+		if ok, _ := s.Sample(level, 1, ""); !ok || s.Enabled(level) {
+			t.Errorf("expected %s entries never sampled", level)
+		}
+	}
+}
+
+func TestSampleEviction(t *testing.T) {
+	s := New(&Options{
+		Rules: map[logrus.Level]Rule{
+			logrus.DebugLevel: {
+				First:    1,
+				Interval: time.Second,
+			},
+		},
+		KeyBy:   KeyByMessage,
+		MaxKeys: 2,
+	})
+
+	ts := time.Date(2022, 7, 20, 11, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		return ts
+	}
+
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		s.Sample(logrus.DebugLevel, 0, msg)
+	}
+
+	// c and d share the overflow counter
+	if len(s.counters) != 3 {
+		t.Errorf("expected keys capped, got %d counters", len(s.counters))
+	}
+
+	if ok, _ := s.Sample(logrus.DebugLevel, 0, "e"); ok {
+		t.Errorf("expected overflowed keys sampled together")
+	}
+
+	ts = ts.Add(time.Second)
+	s.Sample(logrus.DebugLevel, 0, "a")
+
+	// the overflow counter keeps the dropped entry until it is reported
+	if len(s.counters) != 2 {
+		t.Errorf("expected expired counters swept, got %d counters", len(s.counters))
+	}
+}
+
+func TestSampleDroppedAcrossSweep(t *testing.T) {
+	s := New(&Options{
+		Rules: map[logrus.Level]Rule{
+			logrus.DebugLevel: {
+				First:    1,
+				Interval: time.Second,
+			},
+		},
+		KeyBy: KeyByMessage,
+	})
+
+	ts := time.Date(2022, 7, 20, 11, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		return ts
+	}
+
+	for i := 0; i < 10; i++ {
+		s.Sample(logrus.DebugLevel, 0, "a")
+	}
+
+	// the other key sweeps expired counters
+	ts = ts.Add(2 * time.Second)
+	s.Sample(logrus.DebugLevel, 0, "b")
+
+	if ok, sampled := s.Sample(logrus.DebugLevel, 0, "a"); !ok || sampled != 9 {
+		t.Errorf("expected 9 entries dropped before the sweep reported, got %v %d", ok, sampled)
+	}
+
+	ts = ts.Add(2 * time.Second)
+	s.Sample(logrus.DebugLevel, 0, "b")
+
+	if _, found := s.counters[key{level: logrus.DebugLevel, message: "a"}]; found {
+		t.Error("expected the counter swept once dropped entries are reported")
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("debug=100:10:1s, warn=1:0:1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rules[logrus.DebugLevel] != (Rule{100, 10, time.Second}) || rules[logrus.WarnLevel] != (Rule{1, 0, time.Minute}) {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+
+	if _, err := ParseRules("debug=100"); err == nil {
+		t.Errorf("expected error for incomplete rule")
+	}
+}
+
+func expectInts(t *testing.T, actual, expected []int) {
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+	}
+}
+
+func expectUints(t *testing.T, actual, expected []uint64) {
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+	}
+}
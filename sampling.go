@@ -0,0 +1,5 @@
+package suplog
+
+// SampledField is the field name that carries the amount of entries dropped by
+// the sampler since the previous logged entry from the same call site.
+const SampledField = "sampled"
@@ -0,0 +1,99 @@
+package suplog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xlab/suplog/sampler"
+)
+
+func TestSamplingByCaller(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger, err := NewLoggerWithConfig(&Config{
+		Level:     DebugLevel,
+		Formatter: "json",
+		Sampling: &sampler.Options{
+			Rules: map[Level]sampler.Rule{
+				DebugLevel: {
+					First:      1,
+					Thereafter: 5,
+					Interval:   time.Hour,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.(LoggerConfigurator).SetOutput(out)
+
+	for i := 0; i < 6; i++ {
+		logger.Debugf("hot loop %d", i)
+	}
+
+	logger.Debugf("another call site")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got: %s", out.String())
+	}
+
+	if !strings.Contains(lines[1], `"msg":"hot loop 5"`) || !strings.Contains(lines[1], `"sampled":4`) {
+		t.Errorf("expected resumed entry with sampled count, got %s", lines[1])
+	}
+
+	if strings.Contains(lines[2], SampledField) {
+		t.Errorf("expected another call site not to be sampled, got %s", lines[2])
+	}
+}
+
+func TestSamplingByMessage(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger, err := NewLoggerWithConfig(&Config{
+		Level:     DebugLevel,
+		Formatter: "json",
+		Sampling: &sampler.Options{
+			KeyBy: sampler.KeyByMessage,
+			Rules: map[Level]sampler.Rule{
+				WarnLevel: {
+					First:    1,
+					Interval: time.Hour,
+				},
+				PanicLevel: {
+					First:    0,
+					Interval: time.Hour,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.(LoggerConfigurator).SetOutput(out)
+
+	for i := 0; i < 3; i++ {
+		logger.Warningf("retry %d", i)
+		logger.Warningln("attempt", i)
+	}
+
+	func() {
+		defer func() {
+			_ = recover()
+		}()
+
+		logger.Panicf("panic %d", 1)
+	}()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected entries keyed by templates and panic not sampled, got: %s", out.String())
+	}
+
+	if !strings.Contains(lines[2], `"msg":"panic 1"`) {
+		t.Errorf("expected panic entry logged, got %s", lines[2])
+	}
+}
@@ -0,0 +1,496 @@
+//go:build go1.21
+// +build go1.21
+
+package suplog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/xlab/closer"
+	stackHook "github.com/xlab/suplog/hooks/stack"
+	"github.com/xlab/suplog/stackcache"
+)
+
+// NewSlogHandler returns a slog.Handler that logs records into the suplog logger,
+// so hooks fire for records logged by log/slog. Attrs become fields, groups are
+// flattened into dotted field names, the context is passed along with the entry.
+// Provide nil to use DefaultLogger.
+func NewSlogHandler(logger Logger) slog.Handler {
+	if logger == nil {
+		logger = DefaultLogger
+	}
+
+	return &slogHandler{
+		logger: logger,
+	}
+}
+
+type slogHandler struct {
+	logger Logger
+	fields Fields
+	prefix string
+}
+
+// maxSlogCallers limits the call stack captured for hooks.
+const maxSlogCallers = 50
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if configurator, ok := h.logger.(LoggerConfigurator); ok {
+		return configurator.IsLevelEnabled(levelFromSlog(level))
+	}
+
+	return true
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(Fields, len(h.fields)+r.NumAttrs())
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+
+	r.Attrs(func(attr slog.Attr) bool {
+		addSlogAttr(fields, h.prefix, attr)
+		return true
+	})
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if r.PC != 0 {
+		ctx = stackcache.ContextWithCallers(ctx, slogCallers(r.PC))
+	}
+
+	logger := h.logger.WithContext(ctx)
+	if len(fields) > 0 {
+		logger = logger.WithFields(fields)
+	}
+
+	if !r.Time.IsZero() {
+		logger = logger.WithTime(r.Time)
+	}
+
+	logger.Log(levelFromSlog(r.Level), r.Message)
+
+	return nil
+}
+
+// slogCallers captures the call stack, starting from the frame of the record
+// call site, or just that frame if the record is handled elsewhere.
+func slogCallers(pc uintptr) []uintptr {
+	pcs := make([]uintptr, maxSlogCallers)
+	n := runtime.Callers(2, pcs)
+
+	for i, callerPC := range pcs[:n] {
+		if callerPC == pc {
+			return pcs[i:n]
+		}
+	}
+
+	return []uintptr{pc}
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	fields := make(Fields, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+
+	for _, attr := range attrs {
+		addSlogAttr(fields, h.prefix, attr)
+	}
+
+	return &slogHandler{
+		logger: h.logger,
+		fields: fields,
+		prefix: h.prefix,
+	}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if len(name) == 0 {
+		return h
+	}
+
+	return &slogHandler{
+		logger: h.logger,
+		fields: h.fields,
+		prefix: h.prefix + name + ".",
+	}
+}
+
+// addSlogAttr adds the attr into fields, groups are flattened using the dotted prefix.
+func addSlogAttr(fields Fields, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+
+	if value.Kind() == slog.KindGroup {
+		groupAttrs := value.Group()
+		if len(groupAttrs) == 0 {
+			return
+		}
+
+		if len(attr.Key) > 0 {
+			prefix = prefix + attr.Key + "."
+		}
+
+		for _, groupAttr := range groupAttrs {
+			addSlogAttr(fields, prefix, groupAttr)
+		}
+
+		return
+	}
+
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	fields[prefix+attr.Key] = value.Any()
+}
+
+// LogValue evaluates the lazy value for log/slog.
+func (v LazyValue) LogValue() slog.Value {
+	return slog.AnyValue(v.Value())
+}
+
+// levelFromSlog maps slog levels onto suplog levels, never reaching Fatal and Panic.
+func levelFromSlog(level slog.Level) Level {
+	switch {
+	case level < slog.LevelDebug:
+		return TraceLevel
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	default:
+		return ErrorLevel
+	}
+}
+
+// levelToSlog maps suplog levels onto slog levels, Trace, Fatal and Panic
+// are mapped to levels 4 points away from the closest slog level.
+func levelToSlog(level Level) slog.Level {
+	switch level {
+	case TraceLevel:
+		return slog.LevelDebug - 4
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	case FatalLevel:
+		return slog.LevelError + 4
+	default:
+		return slog.LevelError + 8
+	}
+}
+
+// NewSlogLogger returns a Logger that writes into the slog.Handler.
+// Fields become attrs, Fatal exits using closer.Exit and Panic panics after logging.
+func NewSlogLogger(handler slog.Handler) Logger {
+	return &slogLogger{
+		handler: handler,
+		ctx:     context.Background(),
+	}
+}
+
+type slogLogger struct {
+	handler slog.Handler
+	ctx     context.Context
+	time    time.Time
+	name    string
+	// forceStack passes the stack hook marker along with the context
+	forceStack bool
+}
+
+func (l *slogLogger) copy() *slogLogger {
+	return &slogLogger{
+		handler:    l.handler,
+		ctx:        l.ctx,
+		time:       l.time,
+		name:       l.name,
+		forceStack: l.forceStack,
+	}
+}
+
+// slogLogDepth is the amount of frames between runtime.Callers and the logging call site.
+const slogLogDepth = 4
+
+// log sends the record into handler, the message is formatted only if level is enabled.
+func (l *slogLogger) log(level Level, msg func() string) {
+	slogLevel := levelToSlog(level)
+	if !l.handler.Enabled(l.ctx, slogLevel) {
+		return
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(slogLogDepth, pcs[:])
+
+	t := l.time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	ctx := l.ctx
+	if l.forceStack {
+		ctx = stackHook.ContextWithForced(ctx)
+	}
+
+	r := slog.NewRecord(t, slogLevel, msg(), pcs[0])
+	if err := l.handler.Handle(ctx, r); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to handle log record, %v\n", err)
+	}
+}
+
+func (l *slogLogger) logf(level Level, format string, args ...interface{}) {
+	l.log(level, func() string {
+		return fmt.Sprintf(format, args...)
+	})
+}
+
+func (l *slogLogger) logs(level Level, args ...interface{}) {
+	l.log(level, func() string {
+		return fmt.Sprint(args...)
+	})
+}
+
+func (l *slogLogger) logln(level Level, args ...interface{}) {
+	l.log(level, func() string {
+		msg := fmt.Sprintln(args...)
+		return msg[:len(msg)-1]
+	})
+}
+
+func (l *slogLogger) Named(name string) Logger {
+	if len(l.name) > 0 {
+		name = l.name + "." + name
+	}
+
+	outCopy := l.copy()
+	outCopy.name = name
+	outCopy.handler = l.handler.WithAttrs([]slog.Attr{
+		slog.String(LoggerField, name),
+	})
+
+	return outCopy
+}
+
+func (l *slogLogger) WithField(key string, value interface{}) Logger {
+	outCopy := l.copy()
+	outCopy.handler = l.handler.WithAttrs([]slog.Attr{
+		slog.Any(key, value),
+	})
+
+	return outCopy
+}
+
+func (l *slogLogger) WithFields(fields Fields) Logger {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for k, v := range fields {
+		attrs = append(attrs, slog.Any(k, v))
+	}
+
+	outCopy := l.copy()
+	outCopy.handler = l.handler.WithAttrs(attrs)
+
+	return outCopy
+}
+
+func (l *slogLogger) With(fields ...Field) Logger {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, fieldToSlog(f))
+	}
+
+	outCopy := l.copy()
+	outCopy.handler = l.handler.WithAttrs(attrs)
+
+	return outCopy
+}
+
+// fieldToSlog converts the typed field into slog.Attr, keeping its type.
+func fieldToSlog(f Field) slog.Attr {
+	switch f.kind {
+	case stringKind:
+		return slog.String(f.Key, f.str)
+	case intKind:
+		return slog.Int64(f.Key, f.num)
+	case durationKind:
+		return slog.Duration(f.Key, time.Duration(f.num))
+	default:
+		return slog.Any(f.Key, f.iface)
+	}
+}
+
+func (l *slogLogger) WithStack() Logger {
+	outCopy := l.copy()
+	outCopy.forceStack = true
+
+	return outCopy
+}
+
+func (l *slogLogger) WithError(err error) Logger {
+	return l.WithField(logrus.ErrorKey, err)
+}
+
+func (l *slogLogger) WithContext(ctx context.Context) Logger {
+	var outCopy *slogLogger
+	if fields := FieldsFromContext(ctx); len(fields) > 0 {
+		outCopy = l.WithFields(fields).(*slogLogger)
+	} else {
+		outCopy = l.copy()
+	}
+
+	outCopy.ctx = ctx
+
+	return outCopy
+}
+
+func (l *slogLogger) WithTime(t time.Time) Logger {
+	outCopy := l.copy()
+	outCopy.time = t
+
+	return outCopy
+}
+
+func (l *slogLogger) Success(format string, args ...interface{}) {
+	l.WithField(KindField, KindSuccess).(*slogLogger).logf(InfoLevel, format, args...)
+}
+
+func (l *slogLogger) Warning(format string, args ...interface{}) {
+	l.logf(WarnLevel, format, args...)
+}
+
+func (l *slogLogger) Error(format string, args ...interface{}) {
+	l.logf(ErrorLevel, format, args...)
+}
+
+func (l *slogLogger) Debug(format string, args ...interface{}) {
+	l.logf(DebugLevel, format, args...)
+}
+
+func (l *slogLogger) Logf(level Level, format string, args ...interface{}) {
+	l.logf(level, format, args...)
+}
+
+func (l *slogLogger) Tracef(format string, args ...interface{}) {
+	l.logf(TraceLevel, format, args...)
+}
+
+func (l *slogLogger) Debugf(format string, args ...interface{}) {
+	l.logf(DebugLevel, format, args...)
+}
+
+func (l *slogLogger) Infof(format string, args ...interface{}) {
+	l.logf(InfoLevel, format, args...)
+}
+
+func (l *slogLogger) Printf(format string, args ...interface{}) {
+	l.logf(InfoLevel, format, args...)
+}
+
+func (l *slogLogger) Warningf(format string, args ...interface{}) {
+	l.logf(WarnLevel, format, args...)
+}
+
+func (l *slogLogger) Errorf(format string, args ...interface{}) {
+	l.logf(ErrorLevel, format, args...)
+}
+
+func (l *slogLogger) Fatalf(format string, args ...interface{}) {
+	l.logf(FatalLevel, format, args...)
+	closer.Exit(1)
+}
+
+func (l *slogLogger) Panicf(format string, args ...interface{}) {
+	l.logf(PanicLevel, format, args...)
+	panic(fmt.Sprintf(format, args...))
+}
+
+func (l *slogLogger) Log(level Level, args ...interface{}) {
+	l.logs(level, args...)
+}
+
+func (l *slogLogger) Trace(args ...interface{}) {
+	l.logs(TraceLevel, args...)
+}
+
+func (l *slogLogger) Info(args ...interface{}) {
+	l.logs(InfoLevel, args...)
+}
+
+func (l *slogLogger) Print(args ...interface{}) {
+	l.logs(InfoLevel, args...)
+}
+
+func (l *slogLogger) Fatal(args ...interface{}) {
+	l.logs(FatalLevel, args...)
+	closer.Exit(1)
+}
+
+func (l *slogLogger) Panic(args ...interface{}) {
+	l.logs(PanicLevel, args...)
+	panic(fmt.Sprint(args...))
+}
+
+func (l *slogLogger) Logln(level Level, args ...interface{}) {
+	l.logln(level, args...)
+}
+
+func (l *slogLogger) Traceln(args ...interface{}) {
+	l.logln(TraceLevel, args...)
+}
+
+func (l *slogLogger) Debugln(args ...interface{}) {
+	l.logln(DebugLevel, args...)
+}
+
+func (l *slogLogger) Infoln(args ...interface{}) {
+	l.logln(InfoLevel, args...)
+}
+
+func (l *slogLogger) Println(args ...interface{}) {
+	l.logln(InfoLevel, args...)
+}
+
+func (l *slogLogger) Warningln(args ...interface{}) {
+	l.logln(WarnLevel, args...)
+}
+
+func (l *slogLogger) Errorln(args ...interface{}) {
+	l.logln(ErrorLevel, args...)
+}
+
+func (l *slogLogger) Fatalln(args ...interface{}) {
+	l.logln(FatalLevel, args...)
+	closer.Exit(1)
+}
+
+func (l *slogLogger) Panicln(args ...interface{}) {
+	l.logln(PanicLevel, args...)
+	panic(fmt.Sprint(args...))
+}
+
+// Dropped is always zero, the handler writes entries synchronously.
+func (l *slogLogger) Dropped() uint64 {
+	return 0
+}
+
+// LoggedAfterClose is always zero, the handler has no closed state.
+func (l *slogLogger) LoggedAfterClose() uint64 {
+	return 0
+}
@@ -0,0 +1,77 @@
+//go:build go1.21
+// +build go1.21
+
+package suplog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	debugHook "github.com/xlab/suplog/hooks/debug"
+)
+
+func TestSlogHandler(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewLogger(out, new(JSONFormatter), debugHook.NewHook(DefaultLogger, &debugHook.HookOptions{
+		Levels: []Level{ErrorLevel, InfoLevel, DebugLevel},
+	}))
+	logger.(LoggerConfigurator).SetLevel(InfoLevel)
+
+	slogger := slog.New(NewSlogHandler(logger)).With("module", "test")
+	slogger.Debug("not logged")
+	slogger.WithGroup("req").Error("request failed", "id", 42, slog.Any("error", errors.New("boom")))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("expected exactly one JSON entry, got %s: %v", out.String(), err)
+	}
+
+	expected := map[string]interface{}{
+		"level":     "error",
+		"msg":       "request failed",
+		"module":    "test",
+		"req.id":    float64(42),
+		"req.error": "boom",
+		"fn":        "TestSlogHandler",
+	}
+
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, entry[k])
+		}
+	}
+
+	if src, _ := entry["src"].(string); !strings.HasSuffix(src, "slog_test.go:27") {
+		t.Errorf("expected caller to be the slog call site, got %s", src)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := NewSlogLogger(slog.NewJSONHandler(out, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     slog.LevelInfo,
+	}))
+
+	logger.Named("db").WithField("table", "users").WithContext(context.Background()).Debugf("not logged")
+	logger.Named("db").WithField("table", "users").Warningf("slow query: %dms", 500)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("expected exactly one JSON entry, got %s: %v", out.String(), err)
+	}
+
+	if entry["level"] != "WARN" || entry["msg"] != "slow query: 500ms" || entry["table"] != "users" || entry["logger"] != "db" {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+
+	source, _ := entry["source"].(map[string]interface{})
+	if fn, _ := source["function"].(string); !strings.HasSuffix(fn, "TestSlogLogger") {
+		t.Errorf("expected source to be the call site, got %v", source)
+	}
+}
@@ -1,6 +1,7 @@
 package stackcache
 
 import (
+	"context"
 	"runtime"
 	"strings"
 	"sync"
@@ -144,3 +145,38 @@ func GetPackageName(path string) string {
 
 	return path
 }
+
+type callersKey struct{}
+
+// ContextWithCallers returns a copy of ctx carrying program counters of the call stack,
+// starting from the logging call site. Hooks should prefer those over discovering
+// frames on their own, e.g. when the entry is logged through an adapter.
+func ContextWithCallers(ctx context.Context, pcs []uintptr) context.Context {
+	return context.WithValue(ctx, callersKey{}, pcs)
+}
+
+// FramesFromContext returns the call stack frames carried by ctx, if any.
+func FramesFromContext(ctx context.Context) ([]runtime.Frame, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	pcs, ok := ctx.Value(callersKey{}).([]uintptr)
+	if !ok || len(pcs) == 0 {
+		return nil, false
+	}
+
+	frames := runtime.CallersFrames(pcs)
+	stackFrames := make([]runtime.Frame, 0, len(pcs))
+
+	for {
+		f, more := frames.Next()
+		stackFrames = append(stackFrames, f)
+
+		if !more {
+			break
+		}
+	}
+
+	return stackFrames, true
+}
@@ -2,48 +2,42 @@ package suplog
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	debugHook "github.com/xlab/suplog/hooks/debug"
+	stackHook "github.com/xlab/suplog/hooks/stack"
 
 	"github.com/sirupsen/logrus"
 	"github.com/xlab/closer"
+	"github.com/xlab/suplog/sampler"
 	"github.com/xlab/suplog/stackcache"
 )
 
-// NewLogger constructs a new suplogger. The default formatter would be TextFormatter
-// if not overrident by and env variable.
+// NewLogger constructs a new suplogger writing into wr, stderr if nil, configured
+// by ConfigFromEnv. The formatter defaults to the one set by LOG_FORMATTER. Note that
+// the debug and stack hooks are not added by default, all hooks must be provided.
 func NewLogger(wr io.Writer, formatter Formatter, hooks ...Hook) Logger {
+	cfg := ConfigFromEnv()
+
+	if wr == nil {
+		wr = os.Stderr
+	}
+
 	if formatter == nil {
-		switch os.Getenv("LOG_FORMATTER") {
-		case "json":
-			formatter = new(JSONFormatter)
-		default:
-			formatter = new(TextFormatter)
-		}
+		formatter = cfg.newFormatter()
 	}
 
 	log := &suplogger{
-		logger: &logrus.Logger{
-			Out:       wr,
-			Formatter: formatter,
-			Hooks:     make(LevelHooks),
-			Level:     DebugLevel,
-			ExitFunc:  closer.Exit,
-		},
-
-		writer:           wr,
-		mux:              new(sync.Mutex),
-		stackTraceOffset: 0,
-		initDone:         true,
+		initDone: true,
 	}
 
-	log.reloadStackTraceCache()
-	log.entry = log.logger.WithContext(context.Background())
+	log.setup(cfg, wr, formatter)
 
 	for _, h := range hooks {
 		log.AddHook(h)
@@ -52,18 +46,53 @@ func NewLogger(wr io.Writer, formatter Formatter, hooks ...Hook) Logger {
 	return log
 }
 
+// NewLoggerWithConfig constructs a new suplogger using the provided config,
+// including the debug hook, if enabled. Additional hooks are added after.
+// Provide nil config to use ConfigFromEnv.
+func NewLoggerWithConfig(cfg *Config, hooks ...Hook) (Logger, error) {
+	if cfg == nil {
+		cfg = ConfigFromEnv()
+	}
+
+	wr, err := cfg.openOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	log := &suplogger{
+		initDone: true,
+	}
+
+	log.setup(cfg, wr, cfg.newFormatter())
+	log.addDefaultHooks(cfg)
+
+	for _, h := range hooks {
+		log.AddHook(h)
+	}
+
+	return log, nil
+}
+
 type suplogger struct {
 	logger *logrus.Logger
 	entry  *logrus.Entry
+	// fields added by With, merged into the entry only when it is logged
+	fields []Field
+	name   string
+	levels *levelRegistry
 
-	mux              *sync.Mutex
+	life             *lifecycle
 	writer           io.Writer
+	async            *AsyncWriter
+	sampler          *sampler.Sampler
+	dedup            *deduper
 	stack            stackcache.StackCache
 	stackTraceOffset int
+	// forceStack makes the stack hook attach the stack regardless of the level
+	forceStack bool
 
 	init     sync.Once
 	initDone bool
-	closed   bool
 }
 
 func (l *suplogger) initOnce() {
@@ -72,33 +101,77 @@ func (l *suplogger) initOnce() {
 			// bail out if init already done (if New contstructor has been used).
 			return
 		}
+
+		// otherwise init output with defaults and env config
+		cfg := ConfigFromEnv()
 		if l.writer == nil {
-			l.writer = os.Stderr
+			l.writer = cfg.openOutputOrStderr()
 		}
 
-		var formatter Formatter
-		switch os.Getenv("LOG_FORMATTER") {
-		case "json":
-			formatter = new(JSONFormatter)
-		default:
-			formatter = new(TextFormatter)
+		l.setup(cfg, l.writer, cfg.newFormatter())
+		l.addDefaultHooks(cfg)
+		l.initDone = true
+	})
+}
+
+// setup initializes the logger state using the config.
+func (l *suplogger) setup(cfg *Config, wr io.Writer, formatter Formatter) {
+	exitFunc := cfg.ExitFunc
+	if exitFunc == nil {
+		exitFunc = closer.Exit
+	}
+
+	out := wr
+
+	async, isAsync := asyncOutput(wr)
+	if cfg.Async != nil && !isAsync {
+		async, isAsync = NewAsyncWriter(wr, cfg.Async), true
+	}
+
+	if isAsync {
+		if f, ok := formatter.(*levelFormatter); ok {
+			// already wrapped, e.g. when sharing output with hook logger
+			formatter = f.Formatter
 		}
 
-		// otherwise init output with conservative defaults
-		l.logger = &logrus.Logger{
-			Out:       l.writer,
+		l.async = async
+		wr = async
+		out = &levelWriter{
+			w: async,
+		}
+		formatter = &levelFormatter{
 			Formatter: formatter,
-			Hooks:     make(LevelHooks),
-			Level:     DebugLevel,
-			ExitFunc:  closer.Exit,
 		}
+	}
 
-		l.entry = l.logger.WithContext(context.Background())
-		l.reloadStackTraceCache()
-		l.addDefaultHooks()
-		l.mux = new(sync.Mutex)
-		l.initDone = true
-	})
+	level := cfg.Level
+	if level == PanicLevel {
+		// zero value of the unset level
+		level = DebugLevel
+	}
+
+	l.logger = &logrus.Logger{
+		Out:       out,
+		Formatter: formatter,
+		Hooks:     make(LevelHooks),
+		Level:     level,
+		ExitFunc:  exitFunc,
+	}
+
+	if cfg.Sampling != nil {
+		l.sampler = sampler.New(cfg.Sampling)
+	}
+
+	if cfg.Dedup != nil {
+		l.dedup = newDeduper(cfg.Dedup, l.withCaller)
+	}
+
+	l.writer = wr
+	l.life = newLifecycle(cfg)
+	l.levels = newLevelRegistry(l.logger, level)
+	l.levels.setAll(cfg.Levels)
+	l.entry = l.logger.WithContext(context.Background())
+	l.reloadStackTraceCache()
 }
 
 const defaultStackSearchOffset = 1
@@ -110,14 +183,17 @@ func (l *suplogger) reloadStackTraceCache() {
 }
 
 // addDefaultHooks initializes default hooks and additional hooks
-// based on the environment setup.
-func (l *suplogger) addDefaultHooks() {
-	// new logger with same out and formatter, but no hooks.
-	// used to avoid hooking a hooka-roo from hooks,
-	// that hits a mutex in the same logrus entry.
-	hookLogger := NewLogger(l.logger.Out, l.logger.Formatter)
+// based on the config.
+func (l *suplogger) addDefaultHooks(cfg *Config) {
+	hookLogger := l.newHookLogger(cfg)
 
-	l.logger.AddHook(debugHook.NewHook(hookLogger, nil))
+	if cfg.DebugHook != nil {
+		l.logger.AddHook(debugHook.NewHook(hookLogger, cfg.DebugHook))
+	}
+
+	if cfg.StackHook != nil {
+		l.logger.AddHook(stackHook.NewHook(hookLogger, cfg.StackHook))
+	}
 
 	// This has been there for ages, but makes no sense in long run,
 	// also adds too much dependencies into the go mod.
@@ -131,6 +207,45 @@ func (l *suplogger) addDefaultHooks() {
 	// }
 }
 
+// newHookLogger constructs a logger with same out, formatter and levels, but no hooks.
+// Used to avoid hooking a hooka-roo from hooks, that hits a mutex in the same logrus entry.
+// Sampling and dedup are disabled, so hook failures are always reported.
+func (l *suplogger) newHookLogger(cfg *Config) *suplogger {
+	hookCfg := *cfg
+	hookCfg.DebugHook = nil
+	hookCfg.StackHook = nil
+	hookCfg.Sampling = nil
+	hookCfg.Dedup = nil
+	hookCfg.Async = nil
+
+	hookLogger := &suplogger{
+		initDone: true,
+	}
+
+	// async output, if any, is shared with the hook logger
+	hookLogger.setup(&hookCfg, l.logger.Out, l.logger.Formatter)
+
+	return hookLogger
+}
+
+// Named returns a child logger with the name appended to the name of this logger,
+// e.g. "db" and then "pool" results in "db.pool". The name is added as a field and
+// selects the effective level of the logger, see ParseLevels.
+func (l *suplogger) Named(name string) Logger {
+	l.initOnce()
+	if len(l.name) > 0 {
+		name = l.name + "." + name
+	}
+
+	l.levels.register(name)
+
+	outCopy := l.copy()
+	outCopy.name = name
+	outCopy.entry = l.entry.WithField(LoggerField, name)
+
+	return outCopy
+}
+
 // Adds a field to the log entry, note that it doesn't log until you call
 // Debug, Print, Info, Warn, Error, Fatal or Panic. It only creates a log entry.
 // If you want multiple fields, use `WithFields`.
@@ -138,7 +253,8 @@ func (l *suplogger) WithField(key string, value interface{}) Logger {
 	l.initOnce()
 
 	outCopy := l.copy()
-	outCopy.entry = l.entry.WithField(key, value)
+	outCopy.entry = l.pendingEntry().WithField(key, value)
+	outCopy.fields = nil
 
 	return outCopy
 }
@@ -148,7 +264,8 @@ func (l *suplogger) WithField(key string, value interface{}) Logger {
 func (l *suplogger) WithFields(fields Fields) Logger {
 	l.initOnce()
 	outCopy := l.copy()
-	outCopy.entry = l.entry.WithFields(fields)
+	outCopy.entry = l.pendingEntry().WithFields(fields)
+	outCopy.fields = nil
 
 	return outCopy
 }
@@ -158,17 +275,63 @@ func (l *suplogger) WithFields(fields Fields) Logger {
 func (l *suplogger) WithError(err error) Logger {
 	l.initOnce()
 	outCopy := l.copy()
-	outCopy.entry = l.entry.WithError(err)
+	outCopy.entry = l.pendingEntry().WithError(err)
+	outCopy.fields = nil
+
+	return outCopy
+}
+
+// With adds typed fields to the log entry. Fields are kept as is and merged
+// into the entry only when it is logged, so disabled levels cost no allocations
+// besides the logger copy.
+func (l *suplogger) With(fields ...Field) Logger {
+	l.initOnce()
+
+	var outCopy *suplogger
+
+	if n := len(l.fields) + len(fields); n <= inlineFields {
+		// allocate the copy along with its fields
+		c := new(inlineFieldsLogger)
+		outCopy = &c.suplogger
+		l.copyTo(outCopy)
+		outCopy.fields = append(append(c.fields[:0], l.fields...), fields...)
+	} else {
+		outCopy = l.copy()
+		outCopy.fields = appendFields(l.fields, fields)
+	}
+
+	outCopy.entry = l.entry
 
 	return outCopy
 }
 
-// Add a context to the log entry.
+// pendingEntry returns the entry with fields added by With merged in.
+func (l *suplogger) pendingEntry() *logrus.Entry {
+	if len(l.fields) == 0 {
+		return l.entry
+	}
+
+	return entryWithFields(l.entry, l.fields)
+}
+
+// Add a context to the log entry, fields carried by the context are merged
+// into the entry, while fields already set on the entry take precedence.
 func (l *suplogger) WithContext(ctx context.Context) Logger {
 	l.initOnce()
 	outCopy := l.copy()
 	outCopy.entry = l.entry.WithContext(ctx)
 
+	if ctxFields := FieldsFromContext(ctx); len(ctxFields) > 0 {
+		fields := make(Fields, len(ctxFields))
+		for k, v := range ctxFields {
+			if _, ok := l.entry.Data[k]; !ok {
+				fields[k] = v
+			}
+		}
+
+		outCopy.entry = outCopy.entry.WithFields(fields)
+	}
+
 	return outCopy
 }
 
@@ -181,164 +344,344 @@ func (l *suplogger) WithTime(t time.Time) Logger {
 	return outCopy
 }
 
+// WithStack forces the stack hook to attach the stack to the log entry
+// regardless of its level. Requires the stack hook, see Config.StackHook,
+// entries are logged as usual otherwise.
+func (l *suplogger) WithStack() Logger {
+	l.initOnce()
+	outCopy := l.copy()
+	outCopy.entry = l.entry
+	outCopy.forceStack = true
+
+	return outCopy
+}
+
+// logf formats and logs the message, if level is enabled for this logger.
+func (l *suplogger) logf(level Level, format string, args ...interface{}) {
+	if !l.levels.enabled(l.name, level) {
+		return
+	}
+
+	entry, ok := l.sample(level, format)
+	if !ok {
+		return
+	}
+
+	l.write(entry, level, fmt.Sprintf(format, args...))
+}
+
+// log logs the operands formatted like fmt.Sprint, if level is enabled for this logger.
+func (l *suplogger) log(level Level, args ...interface{}) {
+	if !l.levels.enabled(l.name, level) {
+		return
+	}
+
+	entry, ok := l.sample(level, printTemplate(args))
+	if !ok {
+		return
+	}
+
+	l.write(entry, level, fmt.Sprint(args...))
+}
+
+// logln logs the operands formatted like fmt.Sprintln, without the trailing newline.
+func (l *suplogger) logln(level Level, args ...interface{}) {
+	if !l.levels.enabled(l.name, level) {
+		return
+	}
+
+	entry, ok := l.sample(level, printTemplate(args))
+	if !ok {
+		return
+	}
+
+	msg := fmt.Sprintln(args...)
+	l.write(entry, level, msg[:len(msg)-1])
+}
+
+// printTemplate returns the sampling template of Print-style operands: the first
+// operand if it is a string, its type otherwise, so formatted values are not keyed.
+func printTemplate(args []interface{}) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	if s, ok := args[0].(string); ok {
+		return s
+	}
+
+	return fmt.Sprintf("%T", args[0])
+}
+
+// sample checks the sampler, if any, and returns the entry to log. The entry gets
+// the amount of entries dropped since the previous one. Hooks only see sampled entries.
+func (l *suplogger) sample(level Level, template string) (*logrus.Entry, bool) {
+	if l.sampler == nil || !l.sampler.Enabled(level) {
+		return l.entry, true
+	}
+
+	var pc uintptr
+	if l.sampler.KeyBy() == sampler.KeyByCaller {
+		if frames, ok := stackcache.FramesFromContext(l.entry.Context); ok {
+			pc = frames[0].PC
+		} else {
+			pc = l.stack.GetCaller().PC
+		}
+	}
+
+	ok, sampled := l.sampler.Sample(level, pc, template)
+	if !ok {
+		return nil, false
+	} else if sampled > 0 {
+		return l.entry.WithField(SampledField, sampled), true
+	}
+
+	return l.entry, true
+}
+
+// write passes the entry into logrus, all logging calls end up here.
+func (l *suplogger) write(entry *logrus.Entry, level Level, msg string) {
+	if len(l.fields) > 0 {
+		if l.dedup != nil {
+			// dedup keeps the entry until the run of repeats ends
+			entry = entryWithFields(entry, l.fields)
+		} else {
+			pooled := getEntry(entry, l.fields)
+			defer putEntry(pooled)
+
+			entry = pooled
+		}
+	}
+
+	if l.forceStack {
+		entry = entry.WithContext(stackHook.ContextWithForced(entry.Context))
+	}
+
+	entry = resolveLazy(entry)
+
+	if l.life.isClosed() {
+		l.writeClosed(entry, level, msg)
+
+		if level == PanicLevel {
+			panic(msg)
+		}
+
+		return
+	}
+
+	if l.dedup != nil && !l.dedup.check(entry, level, msg) {
+		return
+	}
+
+	entry.Log(level, msg)
+}
+
+// withCaller pins the call site into the entry context, so hooks see
+// the original caller for entries logged later, e.g. dedup summaries.
+func (l *suplogger) withCaller(entry *logrus.Entry) *logrus.Entry {
+	if _, ok := stackcache.FramesFromContext(entry.Context); ok {
+		return entry
+	}
+
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// frame PC points at the call instruction, while callers are return addresses
+	frame := l.stack.GetCaller()
+
+	return entry.WithContext(stackcache.ContextWithCallers(ctx, []uintptr{frame.PC + 1}))
+}
+
+// exit flushes hooks and pending output, then calls the exit func.
+func (l *suplogger) exit(code int) {
+	if !l.life.isClosed() {
+		l.flushHooks(false)
+	}
+
+	if l.async != nil {
+		l.async.Flush()
+	}
+
+	l.logger.Exit(code)
+}
+
 func (l *suplogger) Logf(level Level, format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(level, format, args...)
+	l.logf(level, format, args...)
 }
 
 func (l *suplogger) Tracef(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(TraceLevel, format, args...)
+	l.logf(TraceLevel, format, args...)
 }
 
 func (l *suplogger) Debugf(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(DebugLevel, format, args...)
+	l.logf(DebugLevel, format, args...)
 }
 
 func (l *suplogger) Infof(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(InfoLevel, format, args...)
+	l.logf(InfoLevel, format, args...)
 }
 
 func (l *suplogger) Printf(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Printf(format, args...)
+	l.logf(InfoLevel, format, args...)
 }
 
 func (l *suplogger) Warningf(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(WarnLevel, format, args...)
+	l.logf(WarnLevel, format, args...)
 }
 
 func (l *suplogger) Errorf(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(ErrorLevel, format, args...)
+	l.logf(ErrorLevel, format, args...)
 }
 
 func (l *suplogger) Fatalf(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(FatalLevel, format, args...)
-	l.logger.Exit(1)
+	l.logf(FatalLevel, format, args...)
+	l.exit(1)
 }
 
 func (l *suplogger) Panicf(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(PanicLevel, format, args...)
+	l.logf(PanicLevel, format, args...)
 }
 
 func (l *suplogger) Log(level Level, args ...interface{}) {
 	l.initOnce()
-	l.entry.Log(level, args...)
+	l.log(level, args...)
 }
 
 func (l *suplogger) Trace(args ...interface{}) {
 	l.initOnce()
-	l.entry.Log(TraceLevel, args...)
+	l.log(TraceLevel, args...)
 }
 
 func (l *suplogger) Info(args ...interface{}) {
 	l.initOnce()
-	l.entry.Log(InfoLevel, args...)
+	l.log(InfoLevel, args...)
 }
 
 func (l *suplogger) Print(args ...interface{}) {
 	l.initOnce()
-	l.entry.Print(args...)
+	l.log(InfoLevel, args...)
 }
 
 func (l *suplogger) Fatal(args ...interface{}) {
 	l.initOnce()
-	l.entry.Log(FatalLevel, args...)
-	l.logger.Exit(1)
+	l.log(FatalLevel, args...)
+	l.exit(1)
 }
 
 func (l *suplogger) Panic(args ...interface{}) {
 	l.initOnce()
-	l.entry.Log(PanicLevel, args...)
+	l.log(PanicLevel, args...)
 }
 
 func (l *suplogger) Logln(level Level, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logln(level, args...)
+	l.logln(level, args...)
 }
 
 func (l *suplogger) Traceln(args ...interface{}) {
 	l.initOnce()
-	l.entry.Logln(TraceLevel, args...)
+	l.logln(TraceLevel, args...)
 }
 
 func (l *suplogger) Debugln(args ...interface{}) {
 	l.initOnce()
-	l.entry.Logln(DebugLevel, args...)
+	l.logln(DebugLevel, args...)
 }
 
 func (l *suplogger) Infoln(args ...interface{}) {
 	l.initOnce()
-	l.entry.Logln(InfoLevel, args...)
+	l.logln(InfoLevel, args...)
 }
 
 func (l *suplogger) Println(args ...interface{}) {
 	l.initOnce()
-	l.entry.Println(args...)
+	l.logln(InfoLevel, args...)
 }
 
 func (l *suplogger) Warningln(args ...interface{}) {
 	l.initOnce()
-	l.entry.Logln(WarnLevel, args...)
+	l.logln(WarnLevel, args...)
 }
 
 func (l *suplogger) Errorln(args ...interface{}) {
 	l.initOnce()
-	l.entry.Logln(ErrorLevel, args...)
+	l.logln(ErrorLevel, args...)
 }
 
 func (l *suplogger) Fatalln(args ...interface{}) {
 	l.initOnce()
-	l.entry.Logln(FatalLevel, args...)
-	l.logger.Exit(1)
+	l.logln(FatalLevel, args...)
+	l.exit(1)
 }
 
 func (l *suplogger) Debug(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(DebugLevel, format, args...)
+	l.logf(DebugLevel, format, args...)
 }
 
 func (l *suplogger) Notification(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(InfoLevel, format, args...)
+	l.withKind(KindNotification).logf(InfoLevel, format, args...)
 }
 
 func (l *suplogger) Success(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(InfoLevel, format, args...)
+	l.withKind(KindSuccess).logf(InfoLevel, format, args...)
+}
+
+// withKind returns a copy of the logger that tags Info entries with the kind,
+// the copy is skipped when Info level is disabled anyway.
+func (l *suplogger) withKind(kind string) *suplogger {
+	if !l.levels.enabled(l.name, InfoLevel) {
+		return l
+	}
+
+	outCopy := l.copy()
+	outCopy.entry = l.entry
+	outCopy.fields = appendFields(l.fields, []Field{
+		String(KindField, kind),
+	})
+
+	return outCopy
 }
 
 func (l *suplogger) Warning(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(WarnLevel, format, args...)
+	l.logf(WarnLevel, format, args...)
 }
 
 func (l *suplogger) Error(format string, args ...interface{}) {
 	l.initOnce()
-	l.entry.Logf(ErrorLevel, format, args...)
+	l.logf(ErrorLevel, format, args...)
 }
 
 func (l *suplogger) Panicln(args ...interface{}) {
 	l.initOnce()
-	l.entry.Logln(PanicLevel, args...)
+	l.logln(PanicLevel, args...)
 }
 
-// SetLevel sets the logger level.
+// SetLevel sets the logger level. For a named logger it sets the level
+// of that name, inherited by all its descendants.
 func (l *suplogger) SetLevel(level Level) {
 	l.initOnce()
-	l.logger.SetLevel(level)
+	l.levels.set(l.name, level)
 }
 
-// GetLevel returns the logger level.
+// GetLevel returns the effective logger level.
 func (l *suplogger) GetLevel() Level {
 	l.initOnce()
-	return l.logger.GetLevel()
+	return l.levels.effective(l.name)
 }
 
 // AddHook adds a hook to the logger hooks.
@@ -350,12 +693,18 @@ func (l *suplogger) AddHook(hook Hook) {
 // IsLevelEnabled checks if the log level of the logger is greater than the level param
 func (l *suplogger) IsLevelEnabled(level Level) bool {
 	l.initOnce()
-	return l.logger.IsLevelEnabled(level)
+	return l.levels.enabled(l.name, level)
 }
 
 // SetFormatter sets the logger formatter.
 func (l *suplogger) SetFormatter(formatter Formatter) {
 	l.initOnce()
+	if l.async != nil {
+		formatter = &levelFormatter{
+			Formatter: formatter,
+		}
+	}
+
 	l.logger.SetFormatter(formatter)
 }
 
@@ -366,12 +715,30 @@ func (l *suplogger) SetStackTraceOffset(offset int) {
 	l.reloadStackTraceCache()
 }
 
-// SetOutput sets the logger suplog.
+// SetOutput sets the logger output, closed on Close if it implements io.WriteCloser.
+// In async mode the queue is flushed into the previous output first.
 func (l *suplogger) SetOutput(output io.Writer) {
 	l.initOnce()
+	if l.async != nil {
+		l.async.SetOutput(output)
+		return
+	}
+
+	l.writer = output
 	l.logger.SetOutput(output)
 }
 
+// Dropped returns the amount of entries dropped by the async output due to
+// the queue overflow, always zero for synchronous output.
+func (l *suplogger) Dropped() uint64 {
+	l.initOnce()
+	if l.async == nil {
+		return 0
+	}
+
+	return l.async.Dropped()
+}
+
 // ReplaceHooks replaces the logger hooks and returns the old ones
 func (l *suplogger) ReplaceHooks(hooks LevelHooks) LevelHooks {
 	l.initOnce()
@@ -379,17 +746,26 @@ func (l *suplogger) ReplaceHooks(hooks LevelHooks) LevelHooks {
 }
 
 // Close effectively closes output, closing the underlying writer
-// if it implements io.WriteCloser.
+// if it implements io.WriteCloser. Hooks implementing Flusher and Closer
+// are flushed and closed first. The closed state is shared with all derived
+// loggers, entries logged after Close are handled by Config.AfterClose policy.
 func (l *suplogger) Close() (err error) {
+	l.initOnce()
+
 	// bail out if already closed
-	l.mux.Lock()
-	defer l.mux.Unlock()
+	l.life.mux.Lock()
+	defer l.life.mux.Unlock()
 
-	if l.closed {
+	if l.life.isClosed() {
 		return
 	}
 
-	l.closed = true
+	if l.dedup != nil {
+		l.dedup.flush()
+	}
+
+	atomic.StoreInt32(&l.life.closed, 1)
+	l.flushHooks(true)
 
 	// try to close only WriteClosers
 	if outCloser, ok := l.writer.(io.WriteCloser); ok {
@@ -420,12 +796,23 @@ func isTrue(v string) bool {
 
 // copy allows to construct an suplogger copy with new entry.
 func (l *suplogger) copy() *suplogger {
-	return &suplogger{
-		writer:   l.writer,
-		logger:   l.logger,
-		stack:    l.stack,
-		mux:      l.mux,
-		initDone: l.initDone,
-		closed:   l.closed,
-	}
+	outCopy := new(suplogger)
+	l.copyTo(outCopy)
+
+	return outCopy
+}
+
+func (l *suplogger) copyTo(out *suplogger) {
+	out.fields = l.fields
+	out.writer = l.writer
+	out.async = l.async
+	out.sampler = l.sampler
+	out.dedup = l.dedup
+	out.logger = l.logger
+	out.name = l.name
+	out.levels = l.levels
+	out.stack = l.stack
+	out.life = l.life
+	out.forceStack = l.forceStack
+	out.initDone = l.initDone
 }
@@ -0,0 +1,220 @@
+// Package suplogtest provides an in-memory recording logger for tests,
+// with helpers to assert on logged entries, and helpers for testing hooks.
+package suplogtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xlab/suplog"
+)
+
+// Entry is a recorded log entry. Fields include data added by hooks.
+type Entry struct {
+	Level   suplog.Level
+	Message string
+	Fields  suplog.Fields
+	Time    time.Time
+	Context context.Context
+}
+
+// Recorder is a logger that records every entry after hooks have fired.
+// Entries are recorded on all levels, Fatal entries are recorded without exiting.
+// Loggers derived with WithField, Named, etc. record into the same Recorder.
+type Recorder struct {
+	suplog.Logger
+	suplog.LoggerConfigurator
+
+	mux     sync.Mutex
+	entries []Entry
+}
+
+// NewRecorder creates a new recording logger that writes into wr using
+// the text formatter, provide nil to discard the output. See NewTestWriter
+// to route output into the test log.
+func NewRecorder(wr io.Writer, hooks ...suplog.Hook) *Recorder {
+	logger, err := suplog.NewLoggerWithConfig(&suplog.Config{
+		Level:    suplog.TraceLevel,
+		ExitFunc: func(int) {},
+	}, hooks...)
+	if err != nil {
+		panic(err)
+	}
+
+	if wr == nil {
+		wr = io.Discard
+	}
+
+	r := &Recorder{
+		Logger:             logger,
+		LoggerConfigurator: logger.(suplog.LoggerConfigurator),
+	}
+
+	r.SetOutput(wr)
+	r.SetFormatter(new(suplog.TextFormatter))
+
+	return r
+}
+
+// SetFormatter sets the formatter used for output, entries are still recorded.
+func (r *Recorder) SetFormatter(formatter suplog.Formatter) {
+	r.LoggerConfigurator.SetFormatter(&recordingFormatter{
+		rec:       r,
+		formatter: formatter,
+	})
+}
+
+// recordingFormatter records entries as formatting happens after hooks fire.
+type recordingFormatter struct {
+	rec       *Recorder
+	formatter suplog.Formatter
+}
+
+func (f *recordingFormatter) Format(e *suplog.Entry) ([]byte, error) {
+	f.rec.record(e)
+
+	return f.formatter.Format(e)
+}
+
+func (r *Recorder) record(e *suplog.Entry) {
+	fields := make(suplog.Fields, len(e.Data))
+	for k, v := range e.Data {
+		fields[k] = v
+	}
+
+	r.mux.Lock()
+	r.entries = append(r.entries, Entry{
+		Level:   e.Level,
+		Message: e.Message,
+		Fields:  fields,
+		Time:    e.Time,
+		Context: e.Context,
+	})
+	r.mux.Unlock()
+}
+
+// Entries returns a copy of all recorded entries.
+func (r *Recorder) Entries() []Entry {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	entries := make([]Entry, len(r.entries))
+	copy(entries, r.entries)
+
+	return entries
+}
+
+// LastEntry returns the last recorded entry, or nil if nothing was logged.
+func (r *Recorder) LastEntry() *Entry {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if len(r.entries) == 0 {
+		return nil
+	}
+
+	entry := r.entries[len(r.entries)-1]
+
+	return &entry
+}
+
+// Reset removes all recorded entries.
+func (r *Recorder) Reset() {
+	r.mux.Lock()
+	r.entries = nil
+	r.mux.Unlock()
+}
+
+// Find returns recorded entries on the level, containing msgSubstr in the message
+// and all of the fields. Field values are compared using reflect.DeepEqual.
+func (r *Recorder) Find(level suplog.Level, msgSubstr string, fields suplog.Fields) []Entry {
+	var found []Entry
+
+	for _, entry := range r.Entries() {
+		if entry.matches(level, msgSubstr, fields) {
+			found = append(found, entry)
+		}
+	}
+
+	return found
+}
+
+// AssertLogged reports a test error unless an entry on the level, containing msgSubstr
+// in the message and all of the fields, has been recorded.
+func (r *Recorder) AssertLogged(t testing.TB, level suplog.Level, msgSubstr string, fields suplog.Fields) bool {
+	t.Helper()
+
+	if len(r.Find(level, msgSubstr, fields)) > 0 {
+		return true
+	}
+
+	t.Errorf("expected %s entry %q with fields %v to be logged, recorded entries:\n%s",
+		level, msgSubstr, fields, r.dump())
+
+	return false
+}
+
+// AssertNotLogged reports a test error if an entry on the level, containing msgSubstr
+// in the message and all of the fields, has been recorded.
+func (r *Recorder) AssertNotLogged(t testing.TB, level suplog.Level, msgSubstr string, fields suplog.Fields) bool {
+	t.Helper()
+
+	if len(r.Find(level, msgSubstr, fields)) == 0 {
+		return true
+	}
+
+	t.Errorf("expected %s entry %q with fields %v not to be logged, recorded entries:\n%s",
+		level, msgSubstr, fields, r.dump())
+
+	return false
+}
+
+func (r *Recorder) dump() string {
+	var b strings.Builder
+
+	for _, entry := range r.Entries() {
+		fmt.Fprintf(&b, "\t%s %q %v\n", entry.Level, entry.Message, entry.Fields)
+	}
+
+	return b.String()
+}
+
+func (e *Entry) matches(level suplog.Level, msgSubstr string, fields suplog.Fields) bool {
+	if e.Level != level || !strings.Contains(e.Message, msgSubstr) {
+		return false
+	}
+
+	for k, v := range fields {
+		actual, ok := e.Fields[k]
+		if !ok || !reflect.DeepEqual(actual, v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewTestWriter returns an io.Writer that routes the logger output
+// into t.Log, so it is shown only for failed tests or in verbose mode.
+func NewTestWriter(t testing.TB) io.Writer {
+	return &testWriter{
+		t: t,
+	}
+}
+
+type testWriter struct {
+	t testing.TB
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.t.Helper()
+	w.t.Log(strings.TrimSuffix(string(p), "\n"))
+
+	return len(p), nil
+}
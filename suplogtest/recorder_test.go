@@ -0,0 +1,51 @@
+package suplogtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xlab/suplog"
+	debugHook "github.com/xlab/suplog/hooks/debug"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder(NewTestWriter(t), debugHook.NewHook(suplog.DefaultLogger, nil))
+
+	rec.Info("service started")
+	rec.WithField("user_id", 42).Named("db").Debug("query took %dms", 12)
+
+	err := errors.New("timeout")
+	rec.WithError(err).Fatal("cannot connect")
+
+	if len(rec.Entries()) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(rec.Entries()))
+	}
+
+	rec.AssertLogged(t, suplog.InfoLevel, "started", nil)
+	rec.AssertLogged(t, suplog.DebugLevel, "query took 12ms", suplog.Fields{
+		"user_id":          42,
+		suplog.LoggerField: "db",
+		"fn":               "TestRecorder",
+	})
+	rec.AssertNotLogged(t, suplog.ErrorLevel, "", nil)
+
+	last := rec.LastEntry()
+	if last == nil || last.Level != suplog.FatalLevel || last.Fields["error"] != err {
+		t.Errorf("unexpected last entry: %+v", last)
+	}
+
+	rec.Reset()
+
+	if rec.LastEntry() != nil {
+		t.Errorf("expected no entries after reset")
+	}
+}
+
+func TestRecorderSetFormatter(t *testing.T) {
+	rec := NewRecorder(nil)
+	rec.SetFormatter(new(suplog.JSONFormatter))
+
+	rec.Warning("still recorded")
+
+	rec.AssertLogged(t, suplog.WarnLevel, "still recorded", nil)
+}
@@ -0,0 +1,92 @@
+package suplogtest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xlab/suplog"
+)
+
+// FlushHook flushes the hook, failing the test if it takes longer than 5s.
+func FlushHook(t testing.TB, hook suplog.Hook) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := hook.(suplog.Flusher).Flush(ctx); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+}
+
+// HTTPServer is an httptest stand-in of an HTTP intake API used by hooks,
+// recording accepted requests.
+type HTTPServer struct {
+	*httptest.Server
+
+	mux           sync.Mutex
+	requests      []*http.Request
+	bodies        [][]byte
+	failures      int
+	failureHeader http.Header
+}
+
+// NewHTTPServer starts a server accepting requests to the path with the status,
+// other paths are answered with 404. The server is closed on test cleanup.
+func NewHTTPServer(t testing.TB, path string, status int) *HTTPServer {
+	s := &HTTPServer{}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mux.Lock()
+		defer s.mux.Unlock()
+
+		if r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if s.failures > 0 {
+			s.failures--
+
+			for k, v := range s.failureHeader {
+				w.Header()[k] = v
+			}
+
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		w.WriteHeader(status)
+	}))
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Fail makes the server answer the next n requests with 503 and the header.
+func (s *HTTPServer) Fail(n int, header http.Header) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.failures = n
+	s.failureHeader = header
+}
+
+// Received returns accepted requests along with their bodies.
+func (s *HTTPServer) Received() ([]*http.Request, [][]byte) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.requests, s.bodies
+}